@@ -0,0 +1,34 @@
+package bootstrap
+
+import (
+	"runtime"
+
+	"github.com/pkg/errors"
+	"golang.org/x/exp/slog"
+)
+
+func (b bootstrap) measuresStartupAlloc() bool {
+	return b.allocReport || b.allocLimit > 0
+}
+
+func (b bootstrap) heapAlloc() uint64 {
+	if b.heapAllocFn != nil {
+		return b.heapAllocFn()
+	}
+	ms := runtime.MemStats{}
+	runtime.ReadMemStats(&ms)
+	return ms.HeapAlloc
+}
+
+// checkStartupAlloc reports the heap allocated since before and returns an error
+// if it exceeds the configured limit.
+func (b bootstrap) checkStartupAlloc(logger *slog.Logger, before uint64) error {
+	delta := int64(b.heapAlloc()) - int64(before)
+	if b.allocReport && logger.Enabled(slog.InfoLevel) {
+		logger.Info("startup allocation", slog.Int64("heapAllocDelta", delta))
+	}
+	if b.allocLimit > 0 && delta > int64(b.allocLimit) {
+		return errors.Errorf("startup allocated %d bytes, exceeding the limit of %d bytes", delta, b.allocLimit)
+	}
+	return nil
+}
@@ -0,0 +1,85 @@
+package bootstrap
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/exp/slog"
+)
+
+func heapAllocSeq(values ...uint64) func() uint64 {
+	i := 0
+	return func() uint64 {
+		v := values[i]
+		if i < len(values)-1 {
+			i++
+		}
+		return v
+	}
+}
+
+func Test_bootstrap_heapAlloc(t *testing.T) {
+	assert.NotZero(t, bootstrap{}.heapAlloc())
+	assert.Equal(t, uint64(42), bootstrap{heapAllocFn: heapAllocSeq(42)}.heapAlloc())
+}
+
+func TestBootstrap_Run_startupAlloc(t *testing.T) {
+	t.Run("report", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		logBuf := &bytes.Buffer{}
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		ctx = bufLogCtx(ctx, logBuf)
+		r := NewMockRunner(ctrl)
+		r.EXPECT().Name().Return("testRunner").AnyTimes()
+		r.EXPECT().Run(gomock.Any()).DoAndReturn(func(ctx context.Context) error {
+			<-ctx.Done()
+			return nil
+		})
+		r.EXPECT().Stop(gomock.Any()).Return(nil)
+		b := New(WithRunners(r), WithStartupAllocReport()).(bootstrap)
+		b.heapAllocFn = heapAllocSeq(100, 1100)
+		go func() {
+			<-time.After(time.Millisecond * 10)
+			cancel()
+		}()
+		assert.Nil(t, b.Run(ctx))
+		var reported bool
+		for _, mp := range printAndJson(t, logBuf) {
+			if mp[slog.MessageKey] == "startup allocation" {
+				reported = true
+				assert.Equal(t, float64(1000), mp["heapAllocDelta"])
+			}
+		}
+		assert.True(t, reported)
+	})
+	t.Run("limit", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		logBuf := &bytes.Buffer{}
+		ctx := bufLogCtx(context.Background(), logBuf)
+		r := NewMockRunner(ctrl)
+		r.EXPECT().Name().Return("testRunner").AnyTimes()
+		r.EXPECT().Run(gomock.Any()).DoAndReturn(func(ctx context.Context) error {
+			<-ctx.Done()
+			return nil
+		})
+		stopped := make(chan struct{}, 1)
+		r.EXPECT().Stop(gomock.Any()).DoAndReturn(func(ctx context.Context) error {
+			stopped <- struct{}{}
+			return nil
+		})
+		b := New(WithRunners(r), WithStartupAllocLimit(500)).(bootstrap)
+		b.heapAllocFn = heapAllocSeq(100, 1100)
+		err := b.Run(ctx)
+		assert.NotNil(t, err)
+		assert.Contains(t, err.Error(), "exceeding the limit")
+		<-stopped
+		assert.NotContains(t, logBuf.String(), "bootstrap started.")
+	})
+}
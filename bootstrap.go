@@ -3,12 +3,14 @@ package bootstrap
 import (
 	"context"
 	"fmt"
-	"sync"
+	"os"
+	"reflect"
+	"sort"
 	"time"
 
 	"github.com/pkg/errors"
+	"go.opentelemetry.io/otel/trace"
 	"golang.org/x/exp/slog"
-	"golang.org/x/sync/errgroup"
 
 	"github.com/yimi-go/runner"
 	"github.com/yimi-go/shutdown"
@@ -16,94 +18,646 @@ import (
 )
 
 type Bootstrap interface {
+	// Run starts the runners and blocks until they are stopped.
+	// The runners run with a context derived from ctx, inheriting its deadline. Its logger, see
+	// slog.Ctx, carries the runner name as the "runner" attribute.
+	// The before run and startup phases honor it too, Run returning a *PhaseError if the deadline
+	// is exceeded before they finish.
 	Run(ctx context.Context) error
+	// RunWithResult runs as Run does, and reports the outcome of the run.
+	RunWithResult(ctx context.Context) (RunResult, error)
+	// Health checks the health of the runners implementing HealthChecker.
+	Health(ctx context.Context) error
+	// Pause pauses the runners implementing Pausable.
+	Pause(ctx context.Context) error
+	// Resume resumes the runners implementing Pausable.
+	Resume(ctx context.Context) error
+	// Ready reports whether all runners have started and shutdown has not begun.
+	Ready() bool
+	// Context returns the context of the runners of the ongoing Run, e.g. to tie operations to
+	// the lifecycle. It is done once the runners are to stop, and nil outside of a Run.
+	Context() context.Context
+	// SetValue associates val with key on this Bootstrap.
+	SetValue(key, val any)
+	// Value returns the value associated with key on this Bootstrap, or nil.
+	Value(key any) any
+	// AddRunner adds r to the runners of this Bootstrap.
+	// Whether it is accepted depends on the phase and the dynamic runner window,
+	// see WithDynamicRunnerWindow.
+	AddRunner(r runner.Runner) error
+	// Warnings returns the non-fatal issues met during the last Run.
+	Warnings() []Warning
+	// SetShutdownTimeout sets the timeout of the shutdowns beginning after the call.
+	// It overrides the timeout of the shutdown controller, d <= 0 restores it.
+	SetShutdownTimeout(d time.Duration)
+	// LastError returns the last error returned by the Run or Stop of the runner named name,
+	// during the last Run, or nil.
+	LastError(name string) error
+	// StartedAt returns when the last Run started all its runners, or the zero time before.
+	StartedAt() time.Time
+	// Uptime returns how long the bootstrap has been up since StartedAt, or 0 before.
+	// Once the Run stopped, it no longer grows.
+	Uptime() time.Duration
+	// StopCause returns why the last Run stopped: a *ShutdownError if it was shut down,
+	// the error of the failure or the cause of its context otherwise. It is nil before Run returns.
+	StopCause() error
+	// ShutdownEvent returns the first shutdown event observed during the last Run,
+	// whichever trigger fired it, or nil if it was not shut down by an event.
+	ShutdownEvent() shutdown.Event
+	// Topology returns the JSON description of the runners, in start order. It works before Run.
+	Topology() ([]byte, error)
+	// Validate checks the configuration without starting anything, returning all the problems found.
+	Validate() error
+	// Start runs the Bootstrap in the background, as Run would, and returns once it is ready,
+	// for callers owning the main loop.
+	Start(ctx context.Context) error
+	// Controller returns the shutdown controller, e.g. to add shutdown callbacks before Run.
+	Controller() shutdown.Controller
+	// ShutdownReason returns why the last Run stopped, e.g. ReasonSignal, or ReasonNone.
+	ShutdownReason() Reason
+	// Stop triggers the graceful shutdown of a running Bootstrap, as a shutdown signal would,
+	// and returns once the runners are stopped. If it was started by Start, Stop waits for it to
+	// end and returns what Run would have.
+	Stop(ctx context.Context) error
 }
 
 type bootstrap struct {
+	state     *runState
 	beforeRun func(ctx context.Context) error
 	onRun     func(ctx context.Context) error
 	runners   []runner.Runner
 	gs        shutdown.Controller
+
+	// customShutdown tells gs was set by WithShutdown.
+	customShutdown bool
+	// controllerTriggers are the triggers gs was built with, if declared by WithControllerTriggers.
+	controllerTriggers         []shutdown.Trigger
+	controllerTriggersDeclared bool
+
+	stopTimeout        time.Duration
+	returnStopErrors   bool
+	allocReport        bool
+	allocLimit         uint64
+	heapAllocFn        func() uint64
+	journalPath        string
+	readyDelay         time.Duration
+	triggers           []shutdown.Trigger
+	strictTriggers     bool
+	sortedStartLogs    bool
+	tracer             trace.Tracer
+	metrics            Metrics
+	minUptime          time.Duration
+	onRunLifecycle     OnRunLifecycle
+	forceExit          bool
+	forceExitCode      int
+	optional           map[string]struct{}
+	runnerWindow       RunnerWindow
+	maxConcurrentStart int
+	// dependencies maps runner names to the names of the runners they depend on.
+	dependencies  map[string][]string
+	causeTimeouts map[ShutdownCause]time.Duration
+	onRunnerStart func(ctx context.Context, r runner.Runner)
+	onRunnerStop  func(ctx context.Context, r runner.Runner, err error)
+	// phases maps runner names to their shutdown phases.
+	phases     map[string]int
+	afterReady func(ctx context.Context) error
+	// shutdownErrorHandler handles the errors of the default shutdown controller.
+	shutdownErrorHandler func(ctx context.Context, err error)
+	// extraTriggers are added to the shutdown controller, default or not.
+	extraTriggers    []shutdown.Trigger
+	drainDelay       time.Duration
+	name             string
+	factories        []func(ctx context.Context) ([]runner.Runner, error)
+	beforeRunTimeout time.Duration
+	startupDeadline  time.Duration
+	// runnerTimeouts maps runner names to the timeouts of their runs.
+	runnerTimeouts        map[string]time.Duration
+	onRunTriggersShutdown bool
+	startupBarrier        bool
+	groupConcurrency      int
+	shutdownCtx           func() context.Context
+	loggerAttrsFunc       func(ctx context.Context) []slog.Attr
+	// shutdownTimeout is the timeout of the default shutdown controller, 1s if not set.
+	shutdownTimeout   time.Duration
+	onSignal          func(sig os.Signal)
+	reload            func(ctx context.Context) error
+	startErrorPolicy  StartErrorPolicy
+	runTimeout        time.Duration
+	stopConcurrency   int
+	logSampler        func(runnerName string, event string) bool
+	beforeRunAttempts int
+	beforeRunBackoff  time.Duration
+	waitForChecks     []func(ctx context.Context) error
+	waitForTimeout    time.Duration
+	// stopOrder is the explicit stop order of runner names, see WithStopOrder.
+	stopOrder []string
+	// stopLess orders the runners to stop, see WithShutdownOrderFunc.
+	stopLess func(a, b runner.Runner) bool
+	// stopOrderOverridden tells whether WithStopOrder and WithShutdownOrderFunc replaced each other.
+	stopOrderOverridden bool
+	// nilRunners counts the nil runners given to the options, dropped with a warning by Run.
+	nilRunners    int
+	startupProbe  func(started, total int)
+	shutdownProbe func(stopped, total int)
+	startHooks    []func(ctx context.Context, current []runner.Runner) ([]runner.Runner, error)
+	// supervised maps runner names to their restart policies, see WithSupervised.
+	supervised map[string]SupervisorConfig
+	// runnerLabels maps runner names to their labels, see WithRunnerLabels.
+	runnerLabels map[string]map[string]string
+	// onStarted is called once the bootstrap is ready, by Start.
+	onStarted func()
+	clock     Clock
+	// runnerContexts maps runner names to the funcs deriving their contexts, see WithRunnerContext.
+	runnerContexts map[string]func(parent context.Context) context.Context
+	inFlightGrace  time.Duration
+	// slowStartup is the startup duration beyond which onSlowStartup is called, see WithSlowStartupThreshold.
+	slowStartup   time.Duration
+	onSlowStartup func(d time.Duration)
+	beforeStop    []stopHook
+	debugSignal   os.Signal
+	// startRetries maps runner names to their retry policies, see WithStartRetry.
+	startRetries map[string]startRetry
+	// runTimedOut tells whether the run timeout shut the ongoing Run down.
+	runTimedOut func() bool
 }
 
 func (b bootstrap) Run(ctx context.Context) error {
+	_, err := b.RunWithResult(ctx)
+	return err
+}
+
+// run runs the bootstrap, see Run.
+func (b bootstrap) run(ctx context.Context) (err error) {
 	logger := slog.Ctx(ctx)
-	if len(b.runners) == 0 {
+	if b.name != "" {
+		logger = logger.With(slog.String("bootstrap", b.name))
+		ctx = slog.NewContext(ctx, logger)
+	}
+	if b.loggerAttrsFunc != nil {
+		if attrs := b.loggerAttrsFunc(ctx); len(attrs) > 0 {
+			args := make([]any, 0, len(attrs))
+			for _, attr := range attrs {
+				args = append(args, attr)
+			}
+			logger = logger.With(args...)
+			ctx = slog.NewContext(ctx, logger)
+		}
+	}
+	var level *slog.LevelVar
+	if b.debugSignal != nil {
+		// The lifecycle logs only, ctx keeps the logger for the runners and the hooks.
+		logger, level = withLevelVar(logger)
+	}
+	if !b.hasWork() {
 		logger.Log(slog.ErrorLevel, "no runners, abort.")
 		return nil
 	}
-	before := b.beforeRun
-	if before != nil {
-		if err := before(ctx); err != nil {
+	if b.runTimeout > 0 {
+		parent := ctx
+		timeoutCtx, cancel := context.WithTimeout(ctx, b.runTimeout)
+		ctx = timeoutCtx
+		b.runTimedOut = func() bool {
+			return parent.Err() == nil && errors.Is(timeoutCtx.Err(), context.DeadlineExceeded)
+		}
+		defer func() {
+			cancel()
+			if b.runTimedOut() {
+				err = joinErrors(err, ErrRunTimeout)
+			}
+		}()
+	}
+	b.state.reset()
+	if err := b.checkDependencies(); err != nil {
+		return err
+	}
+	if err := b.checkStopOrder(); err != nil {
+		return err
+	}
+	if b.stopOrderOverridden {
+		if logger.Enabled(slog.WarnLevel) {
+			logger.Warn("Stop order and shutdown order func are mutually exclusive, the last one set is used")
+		}
+		b.warn("", "stop order overridden", nil)
+	}
+	ctx, runSpan := b.startSpan(ctx, "bootstrap.run")
+	defer func() {
+		endSpan(runSpan, err)
+	}()
+	if ctx.Done() == nil && !b.stoppableByTrigger() {
+		if b.strictTriggers {
+			return ErrUnstoppable
+		}
+		if logger.Enabled(slog.WarnLevel) {
+			logger.Warn("Run context is not cancellable and no shutdown trigger is configured, " +
+				"the process can only be stopped by SIGKILL.")
+		}
+		b.warn("", "run context is not cancellable and no shutdown trigger is configured", nil)
+	}
+	var jnl *journal
+	if b.journalPath != "" {
+		if jnl, err = openJournal(b.journalPath); err != nil {
+			return errors.WithMessagef(err, "open journal %s failed", b.journalPath)
+		}
+		jnl.clock = b.clk()
+		defer func() {
+			jnl.record(journalExit, "", err, true)
+			_ = jnl.close()
+		}()
+		jnl.record(journalBoot, "", nil, true)
+	}
+	bootAt := b.clk().Now()
+	b.state.beginRun()
+	defer b.state.store(phaseStopped)
+	var allocBefore uint64
+	if b.measuresStartupAlloc() {
+		allocBefore = b.heapAlloc()
+	}
+	if b.beforeRun != nil {
+		if err := b.runBeforeRun(ctx, logger); err != nil {
 			return err
 		}
 	}
-	eg, egCtx := errgroup.WithContext(ctx)
-	eg.Go(func() error {
-		return b.gs.Wait(egCtx)
+	if len(b.waitForChecks) > 0 {
+		if err := b.waitFor(ctx, logger); err != nil {
+			return err
+		}
+	}
+	runners, err := b.produceRunners(ctx, logger)
+	if err != nil {
+		return err
+	}
+	l := newLifecycle(ctx, b, logger, jnl, bootAt)
+	attached, err := b.state.attach(l, runners)
+	if err != nil {
+		// Runners sharing a name would share a stop callback, reject them before starting any.
+		return l.abort(err)
+	}
+	defer b.state.detach(l)
+	if err := b.checkOrdering(l.runners()); err != nil {
+		return l.abort(err)
+	}
+	if level != nil {
+		l.watchDebugToggle(level)
+	}
+	entries := b.sortEntries(attached)
+	l.checkLabels(entries)
+	// Register all the stop callbacks before the triggers wait, so that none misses an early shutdown.
+	if len(entries) == 0 {
+		l.registerJobStop()
+	}
+	for _, e := range entries {
+		l.registerStop(e.r)
+	}
+	l.registerDynamicStop()
+	if !b.state.armStop() {
+		// Stop was called during the startup, before the callbacks could handle it.
+		return l.abort(ErrStoppedBeforeStart)
+	}
+	l.eg.Go(func() error {
+		return b.gs.Wait(l.triggerCtx)
 	})
-	waitStart := &sync.WaitGroup{}
-	for _, r := range b.runners {
-		r := r
-		b.gs.AddShutdownCallback(shutdown.CallbackFunc(func(ctx context.Context, event shutdown.Event) error {
-			if logger.Enabled(slog.InfoLevel) {
-				logger.Info(fmt.Sprintf("Stopping runner: %s, cause: %s", r.Name(), event.Reason()))
-			}
-			err := r.Stop(ctx)
-			if err != nil {
-				return errors.WithMessagef(err, "stopping %s failed", r.Name())
-			}
-			if logger.Enabled(slog.InfoLevel) {
-				logger.Info(fmt.Sprintf("Runner stoped: %s", r.Name()))
+	if b.sortedStartLogs && logger.Enabled(slog.InfoLevel) {
+		// Runners start concurrently, log them up front in a stable order.
+		names := make([]string, 0, len(entries))
+		for _, e := range entries {
+			names = append(names, e.r.Name())
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if b.sampled(name, LogEventStart) {
+				logger.Info(fmt.Sprintf("Starting runner: %s", name), b.labelArgs(name)...)
 			}
-			return nil
+		}
+	}
+	l.startupTotal = len(entries)
+	for _, e := range entries {
+		l.startRunner(e, true)
+	}
+	started := make(chan struct{})
+	go func() {
+		l.waitStart.Wait()
+		close(started)
+	}()
+	if err := l.awaitStartup(ctx, started); err != nil {
+		b.state.setReason(ReasonRunnerError)
+		l.cancelRun(err)
+		return joinErrors(err, l.endStartup(), l.wait())
+	}
+	if err := l.endStartup(); err != nil {
+		// Runners failed to start with the best effort policy, stop the ones which started.
+		l.failure.CompareAndSwap(nil, &err)
+		b.state.setReason(ReasonRunnerError)
+		b.gs.HandleShutdown(slog.NewContext(context.Background(), logger), shutdown.EventFunc(func() string {
+			return "startup failed"
 		}))
-		waitStart.Add(1)
-		eg.Go(func() error {
-			if logger.Enabled(slog.InfoLevel) {
-				logger.Info(fmt.Sprintf("Starting runner: %s", r.Name()))
-			}
-			waitStart.Done()
-			err := r.Run(egCtx)
-			if err != nil {
-				return errors.WithMessagef(err, "starting %s failed", r.Name())
-			}
-			return nil
-		})
+		return l.wait()
+	}
+	if b.measuresStartupAlloc() {
+		if err := b.checkStartupAlloc(logger, allocBefore); err != nil {
+			// Fail before reporting ready, stopping the runners which started.
+			l.failure.CompareAndSwap(nil, &err)
+			b.gs.HandleShutdown(slog.NewContext(context.Background(), logger), shutdown.EventFunc(func() string {
+				return "startup failed"
+			}))
+			return l.wait()
+		}
 	}
-	waitStart.Wait()
 	if logger.Enabled(slog.InfoLevel) {
 		logger.Info("bootstrap started.")
 	}
-	eg.Go(func() error {
-		fn := b.onRun
-		if fn != nil {
-			err := fn(egCtx)
-			if err != nil {
-				return errors.WithMessagef(err, "onRun err")
+	startedAt := b.clk().Now()
+	b.state.startedAt.Store(&startedAt)
+	b.checkSlowStartup(logger, startedAt.Sub(bootAt))
+	jnl.record(journalReady, "", nil, true)
+	if b.reload != nil {
+		l.watchReload()
+	}
+	l.readyFlipped = b.markReady(l.egCtx)
+	if b.onStarted != nil {
+		go func() {
+			<-l.readyFlipped
+			if b.Ready() && l.allReady() {
+				b.onStarted()
 			}
+		}()
+	}
+	l.runAfterReady()
+	l.runOnRun()
+	err = l.wait()
+	if logger.Enabled(slog.InfoLevel) {
+		attrs := []slog.Attr{
+			slog.Int("stopped", int(l.stoppedCount.Load())),
+			slog.Duration("uptime", b.since(bootAt)),
+		}
+		if event := b.ShutdownEvent(); event != nil {
+			attrs = append(attrs, slog.String("reason", event.Reason()))
+		}
+		attrs = append(attrs, slog.String("shutdown_reason", b.ShutdownReason().String()))
+		if err != nil {
+			attrs = append(attrs, slog.Any(slog.ErrorKey, err))
 		}
+		logger.LogAttrs(slog.InfoLevel, "bootstrap stopped.", attrs...)
+	}
+	return err
+}
+
+// Start runs the bootstrap in the background, and returns once it is ready.
+// If Run returns before, Start returns its error. Stop stops it, returning the result of Run.
+func (b bootstrap) Start(ctx context.Context) error {
+	started := make(chan struct{})
+	done := make(chan error, 1)
+	b.onStarted = func() {
+		close(started)
+	}
+	b.state.mux.Lock()
+	b.state.started = done
+	b.state.mux.Unlock()
+	go func() {
+		done <- b.Run(ctx)
+	}()
+	select {
+	case <-started:
 		return nil
-	})
-	err := eg.Wait()
-	if err != nil && !errors.Is(err, context.Canceled) {
-		return errors.WithMessagef(err, "bootstrap run err")
+	case err := <-done:
+		b.state.takeStarted()
+		return err
+	}
+}
+
+func (b bootstrap) Stop(ctx context.Context) error {
+	done := b.state.takeStarted()
+	switch b.state.load() {
+	case phaseStarting, phaseRunning, phaseStopping:
+		// During the startup, Run returns ErrStoppedBeforeStart once it has registered the stop callbacks.
+		if !b.state.requestStop() {
+			b.gs.HandleShutdown(ctx, shutdown.EventFunc(func() string {
+				return "stop requested"
+			}))
+		}
+	default:
+		if done == nil {
+			return ErrNotRunning
+		}
+	}
+	if done == nil {
+		return nil
+	}
+	// Started by Start, wait for Run to return.
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// produceRunners returns the configured runners followed by the ones produced by the factories,
+// then passes them through the start hooks.
+// Nil runners, given to the options or produced by the factories, are dropped with a warning.
+func (b bootstrap) produceRunners(ctx context.Context, logger *slog.Logger) ([]runner.Runner, error) {
+	runners := append([]runner.Runner(nil), b.runners...)
+	dropped := b.nilRunners
+	for _, factory := range b.factories {
+		rs, err := factory(ctx)
+		if err != nil {
+			return nil, errors.WithMessage(err, "runner factory failed")
+		}
+		for _, r := range rs {
+			if isNilRunner(r) {
+				dropped++
+				continue
+			}
+			runners = append(runners, r)
+		}
+	}
+	if dropped > 0 {
+		if logger.Enabled(slog.WarnLevel) {
+			logger.Warn(fmt.Sprintf("Dropped %d nil runners", dropped))
+		}
+		b.warn("", fmt.Sprintf("dropped %d nil runners", dropped), nil)
+	}
+	for _, hook := range b.startHooks {
+		rs, err := hook(ctx, append([]runner.Runner(nil), runners...))
+		if err != nil {
+			return nil, errors.WithMessage(err, "start hook failed")
+		}
+		if err := checkRunnerSet(rs); err != nil {
+			return nil, errors.WithMessage(err, "start hook returned an invalid runner set")
+		}
+		runners = rs
+	}
+	return runners, nil
+}
+
+// hasWork reports whether Run has anything to run: runners, runner factories or start hooks, which
+// may provide runners, or onRun or beforeRun to run as a job.
+func (b bootstrap) hasWork() bool {
+	return len(b.runnerSet()) > 0 || len(b.factories) > 0 || len(b.startHooks) > 0 ||
+		b.onRun != nil || b.beforeRun != nil
+}
+
+// checkRunnerSet returns an error if rs has nil runners, or runners sharing a name.
+func checkRunnerSet(rs []runner.Runner) error {
+	seen := map[string]bool{}
+	for i, r := range rs {
+		if isNilRunner(r) {
+			return errors.WithMessagef(ErrNilRunner, "runner #%d is nil", i)
+		}
+		if seen[r.Name()] {
+			return errors.WithMessage(ErrDuplicateRunner, r.Name())
+		}
+		seen[r.Name()] = true
 	}
 	return nil
 }
 
+// dropNil returns rs without the nil runners, counting them for Run to warn about.
+func (b *bootstrap) dropNil(rs []runner.Runner) []runner.Runner {
+	kept := make([]runner.Runner, 0, len(rs))
+	for _, r := range rs {
+		if isNilRunner(r) {
+			b.nilRunners++
+			continue
+		}
+		kept = append(kept, r)
+	}
+	return kept
+}
+
+// stoppableByTrigger reports whether a shutdown trigger is configured.
+// A controller set by WithShutdown is assumed to have some, unless its triggers are declared.
+func (b bootstrap) stoppableByTrigger() bool {
+	if b.customShutdown && !b.controllerTriggersDeclared {
+		return true
+	}
+	return len(b.triggers) > 0 || len(b.controllerTriggers) > 0
+}
+
+// isNilRunner reports whether r is nil, or a nil pointer.
+func isNilRunner(r runner.Runner) bool {
+	if r == nil {
+		return true
+	}
+	v := reflect.ValueOf(r)
+	return v.Kind() == reflect.Pointer && v.IsNil()
+}
+
+// isOptional reports whether r has been added by WithOptionalRunners, by name.
+func (b bootstrap) isOptional(r runner.Runner) bool {
+	_, ok := b.optional[r.Name()]
+	return ok
+}
+
+// checkSlowStartup warns if the startup took d, longer than the slow startup threshold.
+func (b bootstrap) checkSlowStartup(logger *slog.Logger, d time.Duration) {
+	if b.slowStartup <= 0 || d <= b.slowStartup {
+		return
+	}
+	if logger.Enabled(slog.WarnLevel) {
+		logger.Warn(fmt.Sprintf("Slow startup: took %s, longer than %s", d, b.slowStartup))
+	}
+	b.warn("", "slow startup", nil)
+	if b.onSlowStartup != nil {
+		b.onSlowStartup(d)
+	}
+}
+
+// markReady flips the readiness, after the ready delay if configured.
+// The returned channel is closed once it is flipped, or ctx is done.
+func (b bootstrap) markReady(ctx context.Context) <-chan struct{} {
+	flipped := make(chan struct{})
+	if b.readyDelay <= 0 {
+		b.state.markRunning()
+		close(flipped)
+		return flipped
+	}
+	go func() {
+		defer close(flipped)
+		select {
+		case <-b.clk().After(b.readyDelay):
+			b.state.markRunning()
+		case <-ctx.Done():
+		}
+	}()
+	return flipped
+}
+
+// waitMinUptime blocks until the bootstrap booted at bootAt has been up for the minimum uptime,
+// or ctx is done.
+func (b bootstrap) waitMinUptime(ctx context.Context, bootAt time.Time) {
+	remaining := b.minUptime - b.since(bootAt)
+	if remaining <= 0 {
+		return
+	}
+	select {
+	case <-b.clk().After(remaining):
+	case <-ctx.Done():
+	}
+}
+
+// stopRunner stops r, bounding the call with the per-runner stop timeout if configured.
+// A runner exceeding its own timeout is abandoned with a warning so that it does not
+// hold the shutdown sequence.
+func (b bootstrap) stopRunner(ctx context.Context, r runner.Runner) error {
+	if b.stopTimeout <= 0 {
+		return r.Stop(ctx)
+	}
+	stopCtx, cancel := context.WithTimeout(ctx, b.stopTimeout)
+	defer cancel()
+	done := make(chan error, 1)
+	go func() {
+		done <- r.Stop(stopCtx)
+	}()
+	select {
+	case err := <-done:
+		return err
+	case <-stopCtx.Done():
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		logger := slog.Ctx(ctx)
+		if logger.Enabled(slog.WarnLevel) {
+			logger.Warn(fmt.Sprintf("Runner stop timed out: %s", r.Name()), slog.Duration("timeout", b.stopTimeout))
+		}
+		b.warn(r.Name(), "stop timed out", stopCtx.Err())
+		return nil
+	}
+}
+
 func New(opts ...Option) Bootstrap {
 	b := bootstrap{
-		gs: shutdown.NewGraceful(
-			shutdown.WithTimeout(time.Second),
-			shutdown.WithErrorHandler(shutdown.ErrorHandleFunc(func(ctx context.Context, err error) {
-				slog.Ctx(ctx).Error("error when shutting down", err)
-			})),
-			shutdown.WithTrigger(posixsignal.NewTrigger()),
-		),
+		state:    &runState{},
+		metrics:  noopMetrics{},
+		triggers: []shutdown.Trigger{posixsignal.NewTrigger()},
+		shutdownErrorHandler: func(ctx context.Context, err error) {
+			slog.Ctx(ctx).Error("error when shutting down", err)
+		},
 	}
 	for _, opt := range opts {
 		opt(&b)
 	}
+	if b.onSignal != nil {
+		b.triggers = observeSignals(b.triggers, b.onSignal)
+		b.extraTriggers = observeSignals(b.extraTriggers, b.onSignal)
+	}
+	if b.gs == nil {
+		b.triggers = append(b.triggers, b.extraTriggers...)
+		timeout := b.shutdownTimeout
+		if timeout <= 0 {
+			timeout = time.Second
+		}
+		b.gs = shutdown.NewGraceful(
+			shutdown.WithTimeout(timeout),
+			shutdown.WithErrorHandler(shutdown.ErrorHandleFunc(b.shutdownErrorHandler)),
+			shutdown.WithTrigger(b.triggers...),
+		)
+		return b
+	}
+	for _, t := range b.extraTriggers {
+		b.gs.AddTrigger(t)
+		b.triggers = append(b.triggers, t)
+	}
+	// The timeout of a custom controller is overridden as by SetShutdownTimeout.
+	b.SetShutdownTimeout(b.shutdownTimeout)
 	return b
 }
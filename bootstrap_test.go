@@ -4,16 +4,25 @@ import (
 	"bufio"
 	"bytes"
 	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"io"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/assert"
 	"golang.org/x/exp/slog"
+
+	"github.com/yimi-go/runner"
+	"github.com/yimi-go/shutdown"
+	"github.com/yimi-go/shutdown/posixsignal"
 )
 
 func TestNew(t *testing.T) {
@@ -32,12 +41,33 @@ func TestNew(t *testing.T) {
 		assert.NotNil(t, b)
 		assert.Equal(t, 1, count)
 	})
+	t.Run("triggers_custom_controller", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		gs, tr := NewMockController(ctrl), NewMockTrigger(ctrl)
+		gs.EXPECT().AddTrigger(tr)
+		b := New(WithTriggers(tr), WithShutdown(gs)).(bootstrap)
+		assert.Same(t, gs, b.gs)
+		assert.Equal(t, []shutdown.Trigger{tr}, b.triggers)
+	})
 }
 
 func bufLogCtx(ctx context.Context, buf *bytes.Buffer) context.Context {
 	return slog.NewContext(ctx, slog.New(slog.NewJSONHandler(buf)).WithContext(ctx))
 }
 
+// lockedWriter serializes the writes to w.
+type lockedWriter struct {
+	mux sync.Mutex
+	w   io.Writer
+}
+
+func (w *lockedWriter) Write(p []byte) (int, error) {
+	w.mux.Lock()
+	defer w.mux.Unlock()
+	return w.w.Write(p)
+}
+
 func printAndJson(t *testing.T, buf *bytes.Buffer) []map[string]any {
 	t.Logf("output: %s", buf.String())
 	var maps []map[string]any
@@ -112,9 +142,13 @@ func TestBootstrap_Run(t *testing.T) {
 		assert.Equal(t, 1, beforeCount)
 		assert.Equal(t, 1, onRunCount)
 		mps := printAndJson(t, logBuf)
-		assert.Len(t, mps, 4)
+		assert.Len(t, mps, 5)
 		assert.Equal(t, slog.InfoLevel.String(), mps[0][slog.LevelKey])
 		assert.Contains(t, mps[0][slog.MessageKey], "Starting runner: ")
+		assert.Equal(t, "bootstrap stopped.", mps[4][slog.MessageKey])
+		assert.EqualValues(t, 1, mps[4]["stopped"])
+		assert.Contains(t, mps[4], "uptime")
+		assert.NotContains(t, mps[4], slog.ErrorKey)
 	})
 	t.Run("before_fail", func(t *testing.T) {
 		ctrl := gomock.NewController(t)
@@ -180,7 +214,7 @@ func TestBootstrap_Run(t *testing.T) {
 		<-stopped
 		assert.Equal(t, 1, onRunCount)
 		mps := printAndJson(t, logBuf)
-		assert.Len(t, mps, 4)
+		assert.Len(t, mps, 5)
 		assert.Equal(t, slog.InfoLevel.String(), mps[0][slog.LevelKey])
 		assert.Contains(t, mps[0][slog.MessageKey], "Starting runner: ")
 	})
@@ -217,7 +251,7 @@ func TestBootstrap_Run(t *testing.T) {
 		wg.Wait()
 		<-stopped
 		mps := printAndJson(t, logBuf)
-		assert.Len(t, mps, 4)
+		assert.Len(t, mps, 5)
 		assert.Equal(t, slog.InfoLevel.String(), mps[0][slog.LevelKey])
 		assert.Contains(t, mps[0][slog.MessageKey], "Starting runner: ")
 	})
@@ -260,8 +294,1195 @@ func TestBootstrap_Run(t *testing.T) {
 		assert.Equal(t, 1, beforeCount)
 		assert.Equal(t, 1, onRunCount)
 		mps := printAndJson(t, logBuf)
-		assert.Len(t, mps, 4)
+		assert.Len(t, mps, 5)
 		assert.Equal(t, slog.InfoLevel.String(), mps[0][slog.LevelKey])
 		assert.Contains(t, mps[0][slog.MessageKey], "Starting runner: ")
+		assert.Equal(t, "bootstrap stopped.", mps[4][slog.MessageKey])
+		assert.Contains(t, mps[4][slog.ErrorKey], "running testRunner failed")
+	})
+	t.Run("stop_timeout", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		logBuf := &bytes.Buffer{}
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		ctx = bufLogCtx(ctx, logBuf)
+		release := make(chan struct{})
+		defer close(release)
+		slow := NewMockRunner(ctrl)
+		slow.EXPECT().Name().Return("slowRunner").MinTimes(1)
+		slow.EXPECT().Run(gomock.Any()).DoAndReturn(func(ctx context.Context) error {
+			<-ctx.Done()
+			return nil
+		})
+		slow.EXPECT().Stop(gomock.Any()).DoAndReturn(func(ctx context.Context) error {
+			<-release
+			return nil
+		})
+		fast := NewMockRunner(ctrl)
+		fast.EXPECT().Name().Return("fastRunner").MinTimes(1)
+		fast.EXPECT().Run(gomock.Any()).DoAndReturn(func(ctx context.Context) error {
+			<-ctx.Done()
+			return nil
+		})
+		stopped := make(chan struct{}, 1)
+		fast.EXPECT().Stop(gomock.Any()).DoAndReturn(func(ctx context.Context) error {
+			stopped <- struct{}{}
+			return nil
+		})
+		b := New(WithRunners(slow, fast), WithStopTimeout(time.Millisecond*20))
+		wg := &sync.WaitGroup{}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := b.Run(ctx)
+			assert.Nil(t, err)
+		}()
+		go func() {
+			<-time.After(time.Millisecond * 10)
+			cancel()
+		}()
+		wg.Wait()
+		<-stopped
+		mps := printAndJson(t, logBuf)
+		var warned bool
+		for _, mp := range mps {
+			if mp[slog.LevelKey] == slog.WarnLevel.String() {
+				warned = true
+				assert.Contains(t, mp[slog.MessageKey], "slowRunner")
+			}
+		}
+		assert.True(t, warned)
 	})
+	t.Run("runner_stop_fail_returned", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		logBuf := &bytes.Buffer{}
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		ctx = bufLogCtx(ctx, logBuf)
+		r := NewMockRunner(ctrl)
+		r.EXPECT().Name().Return("testRunner").MinTimes(1)
+		r.EXPECT().Run(gomock.Any()).DoAndReturn(func(ctx context.Context) error {
+			<-ctx.Done()
+			return nil
+		})
+		stopErr := errors.New("test")
+		r.EXPECT().Stop(gomock.Any()).Return(stopErr)
+		b := New(WithRunners(r), WithReturnStopErrors(true))
+		go func() {
+			<-time.After(time.Millisecond * 10)
+			cancel()
+		}()
+		err := b.Run(ctx)
+		assert.ErrorIs(t, err, stopErr)
+		assert.Contains(t, err.Error(), "stopping testRunner failed")
+		var runnerErr *RunnerError
+		if assert.ErrorAs(t, err, &runnerErr) {
+			assert.Equal(t, "testRunner", runnerErr.Name)
+			assert.Equal(t, RunnerPhaseStop, runnerErr.Phase)
+			assert.Equal(t, stopErr, runnerErr.Err)
+		}
+	})
+	t.Run("unstoppable", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		logBuf := &bytes.Buffer{}
+		ctx := bufLogCtx(context.Background(), logBuf)
+		r := NewMockRunner(ctrl)
+		r.EXPECT().Name().Return("testRunner").AnyTimes()
+		r.EXPECT().Run(gomock.Any()).Return(errors.New("test"))
+		b := New(WithRunners(r), WithShutdown(shutdown.NewGraceful()), WithControllerTriggers())
+		assert.NotNil(t, b.Run(ctx))
+		mps := printAndJson(t, logBuf)
+		assert.Equal(t, slog.WarnLevel.String(), mps[0][slog.LevelKey])
+		assert.Contains(t, mps[0][slog.MessageKey], "SIGKILL")
+	})
+	t.Run("unstoppable_strict", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		r := NewMockRunner(ctrl)
+		b := New(WithRunners(r), WithShutdown(shutdown.NewGraceful()), WithControllerTriggers(),
+			WithStrictShutdownTriggers())
+		assert.ErrorIs(t, b.Run(context.Background()), ErrUnstoppable)
+	})
+	t.Run("custom_controller", func(t *testing.T) {
+		// Its triggers undeclared, or declared, or added.
+		for _, opt := range []Option{
+			WithControllerTriggers(posixsignal.NewTrigger()),
+			WithTriggers(posixsignal.NewTrigger()),
+			func(b *bootstrap) {},
+		} {
+			ctrl := gomock.NewController(t)
+			logBuf := &bytes.Buffer{}
+			ctx := bufLogCtx(context.Background(), logBuf)
+			r := NewMockRunner(ctrl)
+			r.EXPECT().Name().Return("testRunner").AnyTimes()
+			r.EXPECT().Run(gomock.Any()).Return(errors.New("test"))
+			r.EXPECT().Stop(gomock.Any()).Return(nil).AnyTimes()
+			b := New(WithRunners(r), WithShutdown(shutdown.NewGraceful()), opt, WithStrictShutdownTriggers())
+			assert.NotErrorIs(t, b.Run(ctx), ErrUnstoppable)
+			assert.NotContains(t, logBuf.String(), "SIGKILL")
+			ctrl.Finish()
+		}
+	})
+	t.Run("stoppable_by_signal", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		logBuf := &bytes.Buffer{}
+		ctx := bufLogCtx(context.Background(), logBuf)
+		r := NewMockRunner(ctrl)
+		r.EXPECT().Name().Return("testRunner").AnyTimes()
+		r.EXPECT().Run(gomock.Any()).Return(errors.New("test"))
+		r.EXPECT().Stop(gomock.Any()).Return(nil)
+		b := New(WithRunners(r), WithStrictShutdownTriggers())
+		assert.NotErrorIs(t, b.Run(ctx), ErrUnstoppable)
+		for _, mp := range printAndJson(t, logBuf) {
+			assert.NotEqual(t, slog.WarnLevel.String(), mp[slog.LevelKey])
+		}
+	})
+	t.Run("deterministic_start_logging", func(t *testing.T) {
+		names := []string{"e", "b", "d", "a", "c"}
+		for i := 0; i < 3; i++ {
+			ctrl := gomock.NewController(t)
+			logBuf := &bytes.Buffer{}
+			ctx, cancel := context.WithCancel(context.Background())
+			ctx = bufLogCtx(ctx, logBuf)
+			var rs []runner.Runner
+			for _, name := range names {
+				r := NewMockRunner(ctrl)
+				r.EXPECT().Name().Return(name).AnyTimes()
+				r.EXPECT().Run(gomock.Any()).DoAndReturn(func(ctx context.Context) error {
+					<-ctx.Done()
+					return nil
+				})
+				r.EXPECT().Stop(gomock.Any()).Return(nil)
+				rs = append(rs, r)
+			}
+			b := New(WithRunners(rs...), WithDeterministicStartLogging())
+			go func() {
+				<-time.After(time.Millisecond * 10)
+				cancel()
+			}()
+			assert.Nil(t, b.Run(ctx))
+			var started []string
+			for _, mp := range printAndJson(t, logBuf) {
+				msg := mp[slog.MessageKey].(string)
+				if strings.HasPrefix(msg, "Starting runner: ") {
+					started = append(started, strings.TrimPrefix(msg, "Starting runner: "))
+				}
+			}
+			assert.Equal(t, []string{"a", "b", "c", "d", "e"}, started)
+			ctrl.Finish()
+		}
+	})
+	t.Run("min_uptime", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		r := NewMockRunner(ctrl)
+		r.EXPECT().Name().Return("testRunner").AnyTimes()
+		r.EXPECT().Run(gomock.Any()).DoAndReturn(func(ctx context.Context) error {
+			cancel()
+			<-ctx.Done()
+			return nil
+		})
+		var stoppedAfter time.Duration
+		start := time.Now()
+		r.EXPECT().Stop(gomock.Any()).DoAndReturn(func(ctx context.Context) error {
+			stoppedAfter = time.Since(start)
+			return nil
+		})
+		b := New(WithRunners(r), WithMinUptime(time.Millisecond*100))
+		assert.Nil(t, b.Run(ctx))
+		assert.GreaterOrEqual(t, stoppedAfter, time.Millisecond*100)
+	})
+	t.Run("optional_runner_fail", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		logBuf := &bytes.Buffer{}
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		ctx = bufLogCtx(ctx, logBuf)
+		failed := make(chan struct{})
+		optional := NewMockRunner(ctrl)
+		optional.EXPECT().Name().Return("optionalRunner").AnyTimes()
+		optional.EXPECT().Run(gomock.Any()).DoAndReturn(func(ctx context.Context) error {
+			defer close(failed)
+			return errors.New("test")
+		})
+		optional.EXPECT().Stop(gomock.Any()).Return(nil)
+		critical := NewMockRunner(ctrl)
+		critical.EXPECT().Name().Return("criticalRunner").AnyTimes()
+		critical.EXPECT().Run(gomock.Any()).DoAndReturn(func(ctx context.Context) error {
+			<-failed
+			select {
+			case <-ctx.Done():
+				t.Error("critical runner cancelled by optional runner")
+			case <-time.After(time.Millisecond * 10):
+			}
+			cancel()
+			<-ctx.Done()
+			return nil
+		})
+		critical.EXPECT().Stop(gomock.Any()).Return(nil)
+		b := New(WithRunners(critical), WithOptionalRunners(optional))
+		assert.Nil(t, b.Run(ctx))
+		var logged bool
+		for _, mp := range printAndJson(t, logBuf) {
+			if mp[slog.LevelKey] == slog.ErrorLevel.String() {
+				logged = true
+				assert.Contains(t, mp[slog.MessageKey], "optionalRunner")
+			}
+		}
+		assert.True(t, logged)
+	})
+	t.Run("max_concurrent_start", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		var starting, maxStarting atomic.Int32
+		var runners []runner.Runner
+		for _, name := range []string{"r1", "r2", "r3"} {
+			ready := make(chan struct{})
+			r := readierRunner{MockRunner: NewMockRunner(ctrl), waitReady: func(ctx context.Context) error {
+				<-ready
+				return nil
+			}}
+			r.EXPECT().Name().Return(name).AnyTimes()
+			r.EXPECT().Run(gomock.Any()).DoAndReturn(func(ctx context.Context) error {
+				n := starting.Add(1)
+				if n > maxStarting.Load() {
+					maxStarting.Store(n)
+				}
+				time.Sleep(time.Millisecond * 10)
+				starting.Add(-1)
+				close(ready)
+				<-ctx.Done()
+				return nil
+			})
+			r.EXPECT().Stop(gomock.Any()).Return(nil)
+			runners = append(runners, r)
+		}
+		b := New(WithRunners(runners...), WithMaxConcurrentStart(1))
+		done := make(chan error)
+		go func() {
+			done <- b.Run(ctx)
+		}()
+		assert.Eventually(t, func() bool {
+			return b.Ready() && starting.Load() == 0
+		}, time.Second, time.Millisecond)
+		cancel()
+		assert.Nil(t, <-done)
+		assert.Equal(t, int32(1), maxStarting.Load())
+	})
+	t.Run("on_runner_start_stop", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		stopErr := errors.New("test")
+		var runners []runner.Runner
+		for i, name := range []string{"r1", "r2", "r3"} {
+			r := NewMockRunner(ctrl)
+			r.EXPECT().Name().Return(name).AnyTimes()
+			r.EXPECT().Run(gomock.Any()).DoAndReturn(func(ctx context.Context) error {
+				<-ctx.Done()
+				return nil
+			})
+			if i == 0 {
+				r.EXPECT().Stop(gomock.Any()).Return(stopErr)
+			} else {
+				r.EXPECT().Stop(gomock.Any()).Return(nil)
+			}
+			runners = append(runners, r)
+		}
+		var mux sync.Mutex
+		starts, stops := map[string]int{}, map[string]int{}
+		var stopErrs []error
+		b := New(
+			WithRunners(runners...),
+			WithOnRunnerStart(func(ctx context.Context, r runner.Runner) {
+				mux.Lock()
+				defer mux.Unlock()
+				starts[r.Name()]++
+			}),
+			WithOnRunnerStop(func(ctx context.Context, r runner.Runner, err error) {
+				mux.Lock()
+				defer mux.Unlock()
+				stops[r.Name()]++
+				if err != nil {
+					stopErrs = append(stopErrs, err)
+				}
+			}),
+		)
+		done := make(chan error)
+		go func() {
+			done <- b.Run(ctx)
+		}()
+		assert.Eventually(t, b.Ready, time.Second, time.Millisecond)
+		cancel()
+		assert.Nil(t, <-done)
+		want := map[string]int{"r1": 1, "r2": 1, "r3": 1}
+		assert.Equal(t, want, starts)
+		assert.Equal(t, want, stops)
+		assert.Equal(t, []error{stopErr}, stopErrs)
+	})
+	t.Run("after_ready", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		var runners []runner.Runner
+		for _, name := range []string{"r1", "r2", "r3"} {
+			r := NewMockRunner(ctrl)
+			r.EXPECT().Name().Return(name).AnyTimes()
+			r.EXPECT().Run(gomock.Any()).DoAndReturn(func(ctx context.Context) error {
+				<-ctx.Done()
+				return nil
+			})
+			r.EXPECT().Stop(gomock.Any()).Return(nil)
+			runners = append(runners, r)
+		}
+		var b bootstrap
+		observed := make(chan int, 1)
+		b = New(WithRunners(runners...), WithAfterReady(func(ctx context.Context) error {
+			n := 0
+			l := b.state.current
+			for _, e := range l.entries {
+				if e.started.Load() {
+					n++
+				}
+			}
+			observed <- n
+			return nil
+		})).(bootstrap)
+		done := make(chan error)
+		go func() {
+			done <- b.Run(ctx)
+		}()
+		assert.Equal(t, 3, <-observed)
+		cancel()
+		assert.Nil(t, <-done)
+	})
+	t.Run("after_ready_error", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		testErr := errors.New("test")
+		r := NewMockRunner(ctrl)
+		r.EXPECT().Name().Return("testRunner").AnyTimes()
+		stopped := make(chan struct{})
+		r.EXPECT().Run(gomock.Any()).DoAndReturn(func(ctx context.Context) error {
+			<-stopped
+			return nil
+		})
+		r.EXPECT().Stop(gomock.Any()).DoAndReturn(func(ctx context.Context) error {
+			close(stopped)
+			return nil
+		})
+		err := New(WithRunners(r), WithAfterReady(func(ctx context.Context) error {
+			return testErr
+		})).Run(context.Background())
+		assert.ErrorIs(t, err, testErr)
+	})
+	t.Run("shutdown_error_handler", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		stopErr := errors.New("test")
+		r := NewMockRunner(ctrl)
+		r.EXPECT().Name().Return("testRunner").AnyTimes()
+		r.EXPECT().Run(gomock.Any()).DoAndReturn(func(ctx context.Context) error {
+			<-ctx.Done()
+			return nil
+		})
+		r.EXPECT().Stop(gomock.Any()).Return(stopErr)
+		handled := make(chan error, 1)
+		b := New(WithRunners(r), WithShutdownErrorHandler(func(ctx context.Context, err error) {
+			handled <- err
+		}))
+		done := make(chan error)
+		go func() {
+			done <- b.Run(ctx)
+		}()
+		assert.Eventually(t, b.Ready, time.Second, time.Millisecond)
+		cancel()
+		assert.Nil(t, <-done)
+		assert.ErrorIs(t, <-handled, stopErr)
+	})
+	t.Run("triggers", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		fire := make(chan struct{})
+		tr := NewMockTrigger(ctrl)
+		tr.EXPECT().Name().Return("admin").AnyTimes()
+		tr.EXPECT().Wait(gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, c shutdown.Controller) error {
+			select {
+			case <-fire:
+				c.HandleShutdown(ctx, shutdown.EventFunc(func() string {
+					return "admin command"
+				}))
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		})
+		r := NewMockRunner(ctrl)
+		r.EXPECT().Name().Return("testRunner").AnyTimes()
+		stopped := make(chan struct{})
+		r.EXPECT().Run(gomock.Any()).DoAndReturn(func(ctx context.Context) error {
+			<-stopped
+			return nil
+		})
+		r.EXPECT().Stop(gomock.Any()).DoAndReturn(func(ctx context.Context) error {
+			close(stopped)
+			return nil
+		})
+		b := New(WithRunners(r), WithTriggers(tr))
+		assert.Len(t, b.(bootstrap).triggers, 2)
+		done := make(chan error)
+		go func() {
+			done <- b.Run(context.Background())
+		}()
+		assert.Eventually(t, b.Ready, time.Second, time.Millisecond)
+		close(fire)
+		select {
+		case err := <-done:
+			assert.Nil(t, err)
+		case <-time.After(time.Second):
+			t.Error("Run not returned")
+		}
+	})
+	t.Run("drain_delay", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		r := NewMockRunner(ctrl)
+		r.EXPECT().Name().Return("testRunner").AnyTimes()
+		r.EXPECT().Run(gomock.Any()).DoAndReturn(func(ctx context.Context) error {
+			<-ctx.Done()
+			return nil
+		})
+		var stopAt time.Time
+		r.EXPECT().Stop(gomock.Any()).DoAndReturn(func(ctx context.Context) error {
+			stopAt = time.Now()
+			return nil
+		})
+		b := New(WithRunners(r), WithDrainDelay(time.Millisecond*100))
+		done := make(chan error)
+		go func() {
+			done <- b.Run(ctx)
+		}()
+		assert.Eventually(t, b.Ready, time.Second, time.Millisecond)
+		cancelAt := time.Now()
+		cancel()
+		assert.Eventually(t, func() bool {
+			return !b.Ready()
+		}, time.Millisecond*50, time.Millisecond)
+		assert.Nil(t, <-done)
+		assert.GreaterOrEqual(t, stopAt.Sub(cancelAt), time.Millisecond*100)
+	})
+	t.Run("name", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		var wg sync.WaitGroup
+		var bs []Bootstrap
+		bufs := map[string]*bytes.Buffer{}
+		for _, name := range []string{"b1", "b2"} {
+			r := NewMockRunner(ctrl)
+			r.EXPECT().Name().Return("testRunner").AnyTimes()
+			r.EXPECT().Run(gomock.Any()).DoAndReturn(func(ctx context.Context) error {
+				<-ctx.Done()
+				return nil
+			})
+			r.EXPECT().Stop(gomock.Any()).Return(nil)
+			b := New(WithRunners(r), WithName(name))
+			bs = append(bs, b)
+			// The handler of a buffer is not shared, the bootstraps log concurrently.
+			logBuf := &bytes.Buffer{}
+			bufs[name] = logBuf
+			runCtx := bufLogCtx(ctx, logBuf)
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				assert.Nil(t, b.Run(runCtx))
+			}()
+		}
+		assert.Eventually(t, func() bool {
+			return bs[0].Ready() && bs[1].Ready()
+		}, time.Second, time.Millisecond)
+		cancel()
+		wg.Wait()
+		for name, logBuf := range bufs {
+			logs := printAndJson(t, logBuf)
+			assert.NotEmpty(t, logs)
+			for _, m := range logs {
+				assert.Equal(t, name, m["bootstrap"])
+			}
+		}
+	})
+	t.Run("runner_factory", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		var running sync.WaitGroup
+		var produced []runner.Runner
+		for _, name := range []string{"r1", "r2"} {
+			r := NewMockRunner(ctrl)
+			r.EXPECT().Name().Return(name).AnyTimes()
+			running.Add(1)
+			r.EXPECT().Run(gomock.Any()).DoAndReturn(func(ctx context.Context) error {
+				running.Done()
+				<-ctx.Done()
+				return nil
+			})
+			r.EXPECT().Stop(gomock.Any()).Return(nil)
+			produced = append(produced, r)
+		}
+		beforeRun := false
+		b := New(
+			WithBeforeRun(func(ctx context.Context) error {
+				beforeRun = true
+				return nil
+			}),
+			WithRunnerFactory(func(ctx context.Context) ([]runner.Runner, error) {
+				assert.True(t, beforeRun)
+				return produced, nil
+			}),
+		)
+		done := make(chan error)
+		go func() {
+			done <- b.Run(ctx)
+		}()
+		running.Wait()
+		cancel()
+		assert.Nil(t, <-done)
+	})
+	t.Run("runner_factory_error", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		r := NewMockRunner(ctrl)
+		factoryErr := errors.New("test")
+		err := New(WithRunners(r), WithRunnerFactory(func(ctx context.Context) ([]runner.Runner, error) {
+			return nil, factoryErr
+		})).Run(context.Background())
+		assert.ErrorIs(t, err, factoryErr)
+	})
+	t.Run("start_hook", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		var mux sync.Mutex
+		var started []string
+		newRunner := func(name string) *MockRunner {
+			r := NewMockRunner(ctrl)
+			r.EXPECT().Name().Return(name).AnyTimes()
+			return r
+		}
+		newRunning := func(name string) *MockRunner {
+			r := newRunner(name)
+			r.EXPECT().Run(gomock.Any()).DoAndReturn(func(ctx context.Context) error {
+				mux.Lock()
+				started = append(started, name)
+				mux.Unlock()
+				<-ctx.Done()
+				return nil
+			})
+			r.EXPECT().Stop(gomock.Any()).Return(nil)
+			return r
+		}
+		plugin := newRunning("plugin")
+		b := New(
+			WithRunners(newRunning("kept"), newRunner("removed")),
+			WithStartHook(func(ctx context.Context, current []runner.Runner) ([]runner.Runner, error) {
+				var rs []runner.Runner
+				for _, r := range current {
+					if r.Name() != "removed" {
+						rs = append(rs, r)
+					}
+				}
+				return append(rs, plugin), nil
+			}),
+			WithOnRun(func(ctx context.Context) error {
+				cancel()
+				return nil
+			}),
+		)
+		assert.Nil(t, b.Run(ctx))
+		assert.ElementsMatch(t, []string{"kept", "plugin"}, started)
+	})
+	t.Run("start_hook_only", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		r := NewMockRunner(ctrl)
+		r.EXPECT().Name().Return("plugin").AnyTimes()
+		r.EXPECT().Run(gomock.Any()).DoAndReturn(func(ctx context.Context) error {
+			cancel()
+			<-ctx.Done()
+			return nil
+		})
+		r.EXPECT().Stop(gomock.Any()).Return(nil)
+		b := New(WithStartHook(func(ctx context.Context, current []runner.Runner) ([]runner.Runner, error) {
+			assert.Empty(t, current)
+			return []runner.Runner{r}, nil
+		}))
+		assert.Nil(t, b.Run(ctx))
+	})
+	t.Run("start_hook_invalid", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		r := NewMockRunner(ctrl)
+		r.EXPECT().Name().Return("testRunner").AnyTimes()
+		err := New(WithRunners(r), WithStartHook(func(ctx context.Context, current []runner.Runner) ([]runner.Runner, error) {
+			return append(current, current...), nil
+		})).Run(context.Background())
+		assert.ErrorIs(t, err, ErrDuplicateRunner)
+		err = New(WithRunners(r), WithStartHook(func(ctx context.Context, current []runner.Runner) ([]runner.Runner, error) {
+			return append(current, nil), nil
+		})).Run(context.Background())
+		assert.ErrorContains(t, err, "runner #1 is nil")
+		hookErr := errors.New("test")
+		err = New(WithRunners(r), WithStartHook(func(ctx context.Context, current []runner.Runner) ([]runner.Runner, error) {
+			return nil, hookErr
+		})).Run(context.Background())
+		assert.ErrorIs(t, err, hookErr)
+	})
+	t.Run("duplicate_names", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		r1 := NewMockRunner(ctrl)
+		r1.EXPECT().Name().Return("testRunner").AnyTimes()
+		r2 := NewMockRunner(ctrl)
+		r2.EXPECT().Name().Return("testRunner").AnyTimes()
+		done := make(chan error)
+		go func() {
+			done <- New(WithRunners(r1, r2)).Run(context.Background())
+		}()
+		select {
+		case err := <-done:
+			assert.ErrorIs(t, err, ErrDuplicateRunner)
+		case <-time.After(time.Second):
+			t.Fatal("run did not return")
+		}
+	})
+	t.Run("startup_deadline", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		newRunner := func(name string) *MockRunner {
+			r := NewMockRunner(ctrl)
+			r.EXPECT().Name().Return(name).AnyTimes()
+			r.EXPECT().Run(gomock.Any()).DoAndReturn(func(ctx context.Context) error {
+				<-ctx.Done()
+				return nil
+			}).AnyTimes()
+			r.EXPECT().Stop(gomock.Any()).Return(nil).AnyTimes()
+			return r
+		}
+		fast, slow := newRunner("fast"), newRunner("slow")
+		startRunnerHook = func(r runner.Runner) {
+			if r == slow {
+				time.Sleep(time.Millisecond * 200)
+			}
+		}
+		defer func() {
+			startRunnerHook = nil
+		}()
+		err := New(WithRunners(fast, slow), WithStartupDeadline(time.Millisecond*20)).Run(context.Background())
+		var timeoutErr *StartupTimeoutError
+		if assert.ErrorAs(t, err, &timeoutErr) {
+			assert.Equal(t, time.Millisecond*20, timeoutErr.Deadline)
+			assert.Equal(t, []string{"slow"}, timeoutErr.Pending)
+		}
+	})
+	t.Run("runner_timeout", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		batch := NewMockRunner(ctrl)
+		batch.EXPECT().Name().Return("batch").AnyTimes()
+		batch.EXPECT().Run(gomock.Any()).DoAndReturn(func(ctx context.Context) error {
+			<-ctx.Done()
+			return ctx.Err()
+		})
+		batch.EXPECT().Stop(gomock.Any()).Return(nil).AnyTimes()
+		err := New(WithRunners(batch), WithRunnerTimeout("batch", time.Millisecond*10)).Run(context.Background())
+		assert.ErrorIs(t, err, ErrRunnerTimeout)
+		assert.Contains(t, err.Error(), "batch ran longer than 10ms")
+	})
+	t.Run("runner_context", func(t *testing.T) {
+		type credentialKey struct{}
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		seen := map[string]any{}
+		seenMux := &sync.Mutex{}
+		newRunner := func(name string) *MockRunner {
+			r := NewMockRunner(ctrl)
+			r.EXPECT().Name().Return(name).AnyTimes()
+			r.EXPECT().Run(gomock.Any()).DoAndReturn(func(ctx context.Context) error {
+				seenMux.Lock()
+				seen[name] = ctx.Value(credentialKey{})
+				seenMux.Unlock()
+				<-ctx.Done()
+				return nil
+			})
+			r.EXPECT().Stop(gomock.Any()).Return(nil)
+			return r
+		}
+		b := New(WithRunners(newRunner("db"), newRunner("web")),
+			WithRunnerContext("db", func(parent context.Context) context.Context {
+				return context.WithValue(parent, credentialKey{}, "secret")
+			}))
+		done := make(chan error)
+		go func() {
+			done <- b.Run(ctx)
+		}()
+		assert.Eventually(t, b.Ready, time.Second, time.Millisecond)
+		cancel()
+		assert.Nil(t, <-done)
+		assert.Equal(t, map[string]any{"db": "secret", "web": nil}, seen)
+	})
+	t.Run("slow_startup", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		logBuf := &bytes.Buffer{}
+		r := NewMockRunner(ctrl)
+		r.EXPECT().Name().Return("slow").AnyTimes()
+		r.EXPECT().Run(gomock.Any()).DoAndReturn(func(ctx context.Context) error {
+			<-ctx.Done()
+			return nil
+		})
+		r.EXPECT().Stop(gomock.Any()).Return(nil)
+		startRunnerHook = func(r runner.Runner) {
+			time.Sleep(time.Millisecond * 50)
+		}
+		defer func() {
+			startRunnerHook = nil
+		}()
+		measured := make(chan time.Duration, 1)
+		b := New(WithRunners(r), WithSlowStartupThreshold(time.Millisecond*20, func(d time.Duration) {
+			measured <- d
+		}))
+		done := make(chan error)
+		go func() {
+			done <- b.Run(bufLogCtx(ctx, logBuf))
+		}()
+		assert.GreaterOrEqual(t, <-measured, time.Millisecond*50)
+		cancel()
+		assert.Nil(t, <-done)
+		assert.Contains(t, logBuf.String(), "Slow startup: took ")
+		if assert.Len(t, b.Warnings(), 1) {
+			assert.Equal(t, "slow startup", b.Warnings()[0].Message)
+		}
+	})
+	t.Run("runner_timeout_optional", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		batch := NewMockRunner(ctrl)
+		batch.EXPECT().Name().Return("batch").AnyTimes()
+		batch.EXPECT().Run(gomock.Any()).DoAndReturn(func(ctx context.Context) error {
+			<-ctx.Done()
+			return nil
+		})
+		batch.EXPECT().Stop(gomock.Any()).Return(nil).AnyTimes()
+		server := NewMockRunner(ctrl)
+		server.EXPECT().Name().Return("server").AnyTimes()
+		server.EXPECT().Run(gomock.Any()).DoAndReturn(func(runCtx context.Context) error {
+			select {
+			case <-runCtx.Done():
+				t.Error("server stopped by the batch timeout")
+			case <-time.After(time.Millisecond * 50):
+				cancel()
+			}
+			<-runCtx.Done()
+			return nil
+		})
+		server.EXPECT().Stop(gomock.Any()).Return(nil)
+		b := New(WithRunners(server), WithOptionalRunners(batch), WithRunnerTimeout("batch", time.Millisecond*10))
+		assert.Nil(t, b.Run(ctx))
+		assert.ErrorIs(t, b.LastError("batch"), ErrRunnerTimeout)
+	})
+	t.Run("group_concurrency", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		var rs []runner.Runner
+		for i := 0; i < 3; i++ {
+			r := NewMockRunner(ctrl)
+			r.EXPECT().Name().Return(fmt.Sprintf("runner%d", i)).AnyTimes()
+			r.EXPECT().Run(gomock.Any()).DoAndReturn(func(ctx context.Context) error {
+				<-ctx.Done()
+				return nil
+			})
+			r.EXPECT().Stop(gomock.Any()).Return(nil)
+			rs = append(rs, r)
+		}
+		// The internal goroutines take no slot.
+		b := New(WithRunners(rs...), WithGroupConcurrency(len(rs)), WithOnRun(func(ctx context.Context) error {
+			<-ctx.Done()
+			return nil
+		}))
+		done := make(chan error)
+		go func() {
+			done <- b.Run(ctx)
+		}()
+		assert.Eventually(t, b.Ready, time.Second, time.Millisecond)
+		cancel()
+		assert.Nil(t, <-done)
+	})
+	t.Run("logger_attrs_func", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		logBuf := &bytes.Buffer{}
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		ctx = bufLogCtx(ctx, logBuf)
+		r := NewMockRunner(ctrl)
+		r.EXPECT().Name().Return("testRunner").AnyTimes()
+		r.EXPECT().Run(gomock.Any()).DoAndReturn(func(ctx context.Context) error {
+			<-ctx.Done()
+			return nil
+		})
+		r.EXPECT().Stop(gomock.Any()).Return(nil)
+		var runIDs []string
+		b := New(WithRunners(r), WithLoggerAttrsFunc(func(ctx context.Context) []slog.Attr {
+			id := make([]byte, 16)
+			_, _ = rand.Read(id)
+			runID := hex.EncodeToString(id)
+			runIDs = append(runIDs, runID)
+			return []slog.Attr{slog.String("run_id", runID)}
+		}))
+		done := make(chan error)
+		go func() {
+			done <- b.Run(ctx)
+		}()
+		assert.Eventually(t, b.Ready, time.Second, time.Millisecond)
+		cancel()
+		assert.Nil(t, <-done)
+		if assert.Len(t, runIDs, 1) {
+			logs := printAndJson(t, logBuf)
+			startedLogged := false
+			for _, m := range logs {
+				assert.Equal(t, runIDs[0], m["run_id"])
+				if m[slog.MessageKey] == "bootstrap started." {
+					startedLogged = true
+				}
+			}
+			assert.True(t, startedLogged)
+		}
+	})
+	t.Run("stop_hung", func(t *testing.T) {
+		grace := hungStopGrace
+		hungStopGrace = time.Millisecond * 10
+		defer func() {
+			hungStopGrace = grace
+		}()
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		logBuf := &bytes.Buffer{}
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		ctx = bufLogCtx(ctx, logBuf)
+		release := make(chan struct{})
+		r := NewMockRunner(ctrl)
+		r.EXPECT().Name().Return("hungRunner").AnyTimes()
+		r.EXPECT().Run(gomock.Any()).DoAndReturn(func(ctx context.Context) error {
+			<-ctx.Done()
+			return nil
+		})
+		r.EXPECT().Stop(gomock.Any()).DoAndReturn(func(ctx context.Context) error {
+			// Ignores the timeout.
+			<-release
+			return nil
+		})
+		b := New(WithRunners(r))
+		b.SetShutdownTimeout(time.Millisecond * 20)
+		done := make(chan error)
+		go func() {
+			done <- b.Run(ctx)
+		}()
+		assert.Eventually(t, b.Ready, time.Second, time.Millisecond)
+		cancel()
+		assert.Eventually(t, func() bool {
+			return len(b.Warnings()) > 0
+		}, time.Second, time.Millisecond)
+		close(release)
+		assert.ErrorIs(t, <-done, ErrShutdownTimeout)
+		warnings := b.Warnings()
+		if assert.Len(t, warnings, 1) {
+			assert.Equal(t, "hungRunner", warnings[0].Runner)
+		}
+		var warned bool
+		for _, m := range printAndJson(t, logBuf) {
+			if m[slog.LevelKey] == slog.WarnLevel.String() {
+				warned = true
+				assert.Contains(t, m[slog.MessageKey], "did not return from Stop")
+				assert.Contains(t, m[slog.MessageKey], "hungRunner")
+				assert.Contains(t, m, "goroutines")
+			}
+		}
+		assert.True(t, warned)
+	})
+	t.Run("runner_logger", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		logBuf := &bytes.Buffer{}
+		// The runner logs concurrently through a derived handler, not sharing the lock of the base one.
+		ctx := slog.NewContext(context.Background(), slog.New(slog.NewJSONHandler(&lockedWriter{w: logBuf})))
+		r := NewMockRunner(ctrl)
+		r.EXPECT().Name().Return("testRunner").AnyTimes()
+		r.EXPECT().Run(gomock.Any()).DoAndReturn(func(ctx context.Context) error {
+			slog.Ctx(ctx).Info("running")
+			return errors.New("test")
+		})
+		r.EXPECT().Stop(gomock.Any()).Return(nil).AnyTimes()
+		assert.NotNil(t, New(WithRunners(r)).Run(ctx))
+		var logged bool
+		for _, m := range printAndJson(t, logBuf) {
+			if m[slog.MessageKey] == "running" {
+				logged = true
+				assert.Equal(t, "testRunner", m["runner"])
+			} else {
+				assert.NotContains(t, m, "runner")
+			}
+		}
+		assert.True(t, logged)
+	})
+	t.Run("run_timeout", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		r := NewMockRunner(ctrl)
+		r.EXPECT().Name().Return("testRunner").AnyTimes()
+		r.EXPECT().Run(gomock.Any()).DoAndReturn(func(ctx context.Context) error {
+			<-ctx.Done()
+			return nil
+		})
+		r.EXPECT().Stop(gomock.Any()).Return(nil)
+		b := New(WithRunners(r), WithRunTimeout(time.Millisecond*50))
+		start := time.Now()
+		err := b.Run(context.Background())
+		elapsed := time.Since(start)
+		assert.ErrorIs(t, err, ErrRunTimeout)
+		assert.GreaterOrEqual(t, elapsed, time.Millisecond*50)
+		assert.Less(t, elapsed, time.Millisecond*500)
+	})
+	t.Run("stop_concurrency", func(t *testing.T) {
+		run := func(t *testing.T, n int) int32 {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			var stopping, maxStopping atomic.Int32
+			var rs []runner.Runner
+			for i := 0; i < 3; i++ {
+				r := NewMockRunner(ctrl)
+				r.EXPECT().Name().Return(fmt.Sprintf("runner%d", i)).AnyTimes()
+				r.EXPECT().Run(gomock.Any()).DoAndReturn(func(ctx context.Context) error {
+					<-ctx.Done()
+					return nil
+				})
+				r.EXPECT().Stop(gomock.Any()).DoAndReturn(func(ctx context.Context) error {
+					current := stopping.Add(1)
+					defer stopping.Add(-1)
+					for {
+						max := maxStopping.Load()
+						if current <= max || maxStopping.CompareAndSwap(max, current) {
+							break
+						}
+					}
+					time.Sleep(time.Millisecond * 20)
+					return nil
+				})
+				rs = append(rs, r)
+			}
+			b := New(WithRunners(rs...), WithStopConcurrency(n))
+			done := make(chan error)
+			go func() {
+				done <- b.Run(ctx)
+			}()
+			assert.Eventually(t, b.Ready, time.Second, time.Millisecond)
+			cancel()
+			assert.Nil(t, <-done)
+			return maxStopping.Load()
+		}
+		assert.Equal(t, int32(1), run(t, 1))
+		assert.Equal(t, int32(3), run(t, 0))
+	})
+	t.Run("log_sampler", func(t *testing.T) {
+		for _, sorted := range []bool{false, true} {
+			ctrl := gomock.NewController(t)
+			logBuf := &bytes.Buffer{}
+			ctx, cancel := context.WithCancel(context.Background())
+			ctx = bufLogCtx(ctx, logBuf)
+			var rs []runner.Runner
+			for i := 0; i < 3; i++ {
+				r := NewMockRunner(ctrl)
+				r.EXPECT().Name().Return(fmt.Sprintf("runner%d", i)).AnyTimes()
+				r.EXPECT().Run(gomock.Any()).DoAndReturn(func(ctx context.Context) error {
+					<-ctx.Done()
+					return nil
+				})
+				r.EXPECT().Stop(gomock.Any()).Return(nil)
+				rs = append(rs, r)
+			}
+			opts := []Option{WithRunners(rs...), WithLogSampler(func(runnerName string, event string) bool {
+				return false
+			})}
+			if sorted {
+				opts = append(opts, WithDeterministicStartLogging())
+			}
+			b := New(opts...)
+			done := make(chan error)
+			go func() {
+				done <- b.Run(ctx)
+			}()
+			assert.Eventually(t, b.Ready, time.Second, time.Millisecond)
+			cancel()
+			assert.Nil(t, <-done)
+			var msgs []any
+			for _, m := range printAndJson(t, logBuf) {
+				msgs = append(msgs, m[slog.MessageKey])
+			}
+			assert.Equal(t, []any{"bootstrap started.", "bootstrap stopped."}, msgs)
+			ctrl.Finish()
+		}
+	})
+}
+
+func TestBootstrap_Run_timeouts(t *testing.T) {
+	// newRunner returns a runner running until its context is done, which reports the timeout of
+	// the context it is stopped with to timeout, and stops after stopDelay.
+	newRunner := func(ctrl *gomock.Controller, timeout chan<- time.Duration, stopDelay time.Duration) *MockRunner {
+		r := NewMockRunner(ctrl)
+		r.EXPECT().Name().Return("testRunner").AnyTimes()
+		r.EXPECT().Run(gomock.Any()).DoAndReturn(func(ctx context.Context) error {
+			<-ctx.Done()
+			return nil
+		}).AnyTimes()
+		r.EXPECT().Stop(gomock.Any()).DoAndReturn(func(ctx context.Context) error {
+			if deadline, ok := ctx.Deadline(); ok && timeout != nil {
+				timeout <- time.Until(deadline)
+			}
+			time.Sleep(stopDelay)
+			return nil
+		}).AnyTimes()
+		return r
+	}
+	t.Run("before_run", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		err := New(WithRunners(newRunner(ctrl, nil, 0)), WithTimeouts(Timeouts{BeforeRun: time.Millisecond * 10}),
+			WithBeforeRun(func(ctx context.Context) error {
+				<-ctx.Done()
+				return ctx.Err()
+			})).Run(context.Background())
+		var phaseErr *PhaseError
+		if assert.ErrorAs(t, err, &phaseErr) {
+			assert.Equal(t, "before run", phaseErr.Phase)
+		}
+	})
+	t.Run("startup", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		startRunnerHook = func(runner.Runner) {
+			time.Sleep(time.Millisecond * 100)
+		}
+		defer func() {
+			startRunnerHook = nil
+		}()
+		err := New(WithRunners(newRunner(ctrl, nil, 0)), WithTimeouts(Timeouts{Startup: time.Millisecond * 10})).
+			Run(context.Background())
+		assert.ErrorAs(t, err, new(*StartupTimeoutError))
+	})
+	run := func(t *testing.T, opts ...Option) Bootstrap {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		b := New(opts...)
+		done := make(chan error)
+		go func() {
+			done <- b.Run(ctx)
+		}()
+		assert.Eventually(t, b.Ready, time.Second, time.Millisecond)
+		cancel()
+		assert.Nil(t, <-done)
+		return b
+	}
+	t.Run("shutdown", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		timeout := make(chan time.Duration, 1)
+		run(t, WithRunners(newRunner(ctrl, timeout, 0)), WithTimeouts(Timeouts{Shutdown: time.Millisecond * 100}))
+		assert.LessOrEqual(t, <-timeout, time.Millisecond*100)
+	})
+	t.Run("shutdown_custom_controller", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		timeout := make(chan time.Duration, 1)
+		run(t, WithRunners(newRunner(ctrl, timeout, 0)),
+			WithShutdown(shutdown.NewGraceful(
+				shutdown.WithTimeout(time.Minute),
+				shutdown.WithTrigger(posixsignal.NewTrigger()),
+			)),
+			WithTimeouts(Timeouts{Shutdown: time.Millisecond * 100}))
+		assert.LessOrEqual(t, <-timeout, time.Millisecond*100)
+	})
+	t.Run("per_runner_stop", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		b := run(t, WithRunners(newRunner(ctrl, nil, time.Millisecond*200)),
+			WithTimeouts(Timeouts{PerRunnerStop: time.Millisecond * 10}))
+		if assert.Len(t, b.Warnings(), 1) {
+			assert.Equal(t, "stop timed out", b.Warnings()[0].Message)
+		}
+	})
+}
+
+func TestBootstrap_Run_nilRunners(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	logBuf := &bytes.Buffer{}
+	ctx, cancel := context.WithCancel(bufLogCtx(context.Background(), logBuf))
+	defer cancel()
+	r := NewMockRunner(ctrl)
+	r.EXPECT().Name().Return("testRunner").AnyTimes()
+	r.EXPECT().Run(gomock.Any()).DoAndReturn(func(ctx context.Context) error {
+		<-ctx.Done()
+		return nil
+	})
+	r.EXPECT().Stop(gomock.Any()).Return(nil)
+	var nilRunner *MockRunner
+	// The valid runners next to the nil ones run.
+	b := New(WithRunners(nil, r), WithRunnerFactory(func(ctx context.Context) ([]runner.Runner, error) {
+		return []runner.Runner{nilRunner}, nil
+	}), WithOnRun(func(ctx context.Context) error {
+		cancel()
+		return nil
+	}))
+	assert.NotPanics(t, func() {
+		assert.Nil(t, b.Validate())
+		assert.Nil(t, b.Run(ctx))
+	})
+	var warned bool
+	for _, m := range printAndJson(t, logBuf) {
+		if m[slog.MessageKey] == "Dropped 2 nil runners" {
+			warned = m[slog.LevelKey] == slog.WarnLevel.String()
+		}
+	}
+	assert.True(t, warned)
+	assert.Contains(t, b.Warnings(), Warning{Message: "dropped 2 nil runners"})
 }
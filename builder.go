@@ -0,0 +1,50 @@
+package bootstrap
+
+import (
+	"context"
+
+	"github.com/yimi-go/runner"
+	"github.com/yimi-go/shutdown"
+)
+
+// Builder accumulates the options of a Bootstrap, e.g. when they depend on configuration.
+// The zero value is ready to use.
+type Builder struct {
+	opts []Option
+}
+
+// NewBuilder creates a Builder starting with opts.
+func NewBuilder(opts ...Option) *Builder {
+	return &Builder{opts: opts}
+}
+
+// With adds opts.
+func (bd *Builder) With(opts ...Option) *Builder {
+	bd.opts = append(bd.opts, opts...)
+	return bd
+}
+
+// AddRunner adds runners, see WithRunners.
+func (bd *Builder) AddRunner(rs ...runner.Runner) *Builder {
+	return bd.With(WithRunners(rs...))
+}
+
+// BeforeRun sets the before run func, see WithBeforeRun.
+func (bd *Builder) BeforeRun(fn func(ctx context.Context) error) *Builder {
+	return bd.With(WithBeforeRun(fn))
+}
+
+// OnRun sets the on run func, see WithOnRun.
+func (bd *Builder) OnRun(fn func(ctx context.Context) error) *Builder {
+	return bd.With(WithOnRun(fn))
+}
+
+// Shutdown sets the shutdown controller, see WithShutdown.
+func (bd *Builder) Shutdown(gs shutdown.Controller) *Builder {
+	return bd.With(WithShutdown(gs))
+}
+
+// Build creates the Bootstrap with the accumulated options.
+func (bd *Builder) Build() Bootstrap {
+	return New(bd.opts...)
+}
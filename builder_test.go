@@ -0,0 +1,45 @@
+package bootstrap
+
+import (
+	"context"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/yimi-go/runner"
+)
+
+func TestBuilder(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	r1, r2 := NewMockRunner(ctrl), NewMockRunner(ctrl)
+	t.Run("minimal", func(t *testing.T) {
+		var bd Builder
+		b := bd.AddRunner(r1).Build().(bootstrap)
+		assert.Equal(t, []runner.Runner{r1}, b.runners)
+		assert.Nil(t, b.beforeRun)
+		assert.Nil(t, b.onRun)
+		assert.NotNil(t, b.gs)
+	})
+	t.Run("full", func(t *testing.T) {
+		gs := NewMockController(ctrl)
+		fn := func(ctx context.Context) error {
+			return nil
+		}
+		bd := NewBuilder(WithStopTimeout(1))
+		for _, enabled := range []bool{true, false} {
+			if enabled {
+				bd.AddRunner(r1)
+			} else {
+				bd.AddRunner(r2)
+			}
+		}
+		b := bd.BeforeRun(fn).OnRun(fn).Shutdown(gs).Build().(bootstrap)
+		assert.Equal(t, []runner.Runner{r1, r2}, b.runners)
+		assert.NotNil(t, b.beforeRun)
+		assert.NotNil(t, b.onRun)
+		assert.Same(t, gs, b.gs)
+		assert.EqualValues(t, 1, b.stopTimeout)
+	})
+}
@@ -0,0 +1,72 @@
+package bootstrap
+
+import (
+	"github.com/yimi-go/runner"
+)
+
+// Capability is an optional interface a runner may implement, see Capabilities.
+type Capability string
+
+const (
+	// CapabilityReadier is implemented by the runners signaling their readiness, see Readier.
+	CapabilityReadier Capability = "readier"
+	// CapabilityPausable is implemented by the runners which can be paused, see Pausable.
+	CapabilityPausable Capability = "pausable"
+	// CapabilityHealthChecker is implemented by the runners reporting their health, see HealthChecker.
+	CapabilityHealthChecker Capability = "health checker"
+	// CapabilityPrioritizer is implemented by the runners with a shutdown priority, see Prioritizer.
+	CapabilityPrioritizer Capability = "prioritizer"
+	// CapabilityRequirer is implemented by the runners with requirements, see Requirer.
+	CapabilityRequirer Capability = "requirer"
+)
+
+// AsReadier returns r as a Readier, if it implements it.
+func AsReadier(r runner.Runner) (Readier, bool) {
+	rd, ok := r.(Readier)
+	return rd, ok
+}
+
+// AsPausable returns r as a Pausable, if it implements it.
+func AsPausable(r runner.Runner) (Pausable, bool) {
+	p, ok := r.(Pausable)
+	return p, ok
+}
+
+// AsHealthChecker returns r as a HealthChecker, if it implements it.
+func AsHealthChecker(r runner.Runner) (HealthChecker, bool) {
+	hc, ok := r.(HealthChecker)
+	return hc, ok
+}
+
+// AsPrioritizer returns r as a Prioritizer, if it implements it.
+func AsPrioritizer(r runner.Runner) (Prioritizer, bool) {
+	p, ok := r.(Prioritizer)
+	return p, ok
+}
+
+// AsRequirer returns r as a Requirer, if it implements it.
+func AsRequirer(r runner.Runner) (Requirer, bool) {
+	rq, ok := r.(Requirer)
+	return rq, ok
+}
+
+// Capabilities returns the set of the optional interfaces r implements, e.g. for admin endpoints.
+func Capabilities(r runner.Runner) map[Capability]struct{} {
+	caps := map[Capability]struct{}{}
+	if _, ok := AsReadier(r); ok {
+		caps[CapabilityReadier] = struct{}{}
+	}
+	if _, ok := AsPausable(r); ok {
+		caps[CapabilityPausable] = struct{}{}
+	}
+	if _, ok := AsHealthChecker(r); ok {
+		caps[CapabilityHealthChecker] = struct{}{}
+	}
+	if _, ok := AsPrioritizer(r); ok {
+		caps[CapabilityPrioritizer] = struct{}{}
+	}
+	if _, ok := AsRequirer(r); ok {
+		caps[CapabilityRequirer] = struct{}{}
+	}
+	return caps
+}
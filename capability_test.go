@@ -0,0 +1,51 @@
+package bootstrap
+
+import (
+	"context"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+// pausableReadierRunner is a runner implementing both Readier and Pausable.
+type pausableReadierRunner struct {
+	readierRunner
+}
+
+func (pausableReadierRunner) Pause(ctx context.Context) error {
+	return nil
+}
+
+func (pausableReadierRunner) Resume(ctx context.Context) error {
+	return nil
+}
+
+func TestCapabilities(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	plain := NewMockRunner(ctrl)
+	assert.Empty(t, Capabilities(plain))
+	_, ok := AsReadier(plain)
+	assert.False(t, ok)
+
+	r := pausableReadierRunner{readierRunner{MockRunner: NewMockRunner(ctrl), waitReady: func(ctx context.Context) error {
+		return nil
+	}}}
+	assert.Equal(t, map[Capability]struct{}{
+		CapabilityReadier:  {},
+		CapabilityPausable: {},
+	}, Capabilities(r))
+	if rd, ok := AsReadier(r); assert.True(t, ok) {
+		assert.Nil(t, rd.WaitReady(context.Background()))
+	}
+	if p, ok := AsPausable(r); assert.True(t, ok) {
+		assert.Nil(t, p.Pause(context.Background()))
+	}
+	_, ok = AsHealthChecker(r)
+	assert.False(t, ok)
+	_, ok = AsPrioritizer(r)
+	assert.False(t, ok)
+	_, ok = AsRequirer(r)
+	assert.False(t, ok)
+}
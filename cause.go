@@ -0,0 +1,150 @@
+package bootstrap
+
+import (
+	"context"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/yimi-go/shutdown"
+)
+
+// ShutdownCause is what triggered a shutdown. Causes other than the predefined ones are
+// the reasons of the shutdown events, e.g. of custom triggers.
+type ShutdownCause string
+
+const (
+	// CauseSignal is a shutdown triggered by a POSIX signal.
+	CauseSignal ShutdownCause = "signal"
+	// CauseContextDone is a shutdown triggered by the Run context being done.
+	CauseContextDone ShutdownCause = "context done"
+	// CauseStopRequested is a shutdown triggered by Stop.
+	CauseStopRequested ShutdownCause = "stop requested"
+	// CauseOnRunCompleted is a shutdown triggered by onRun returning, see WithOnRunTriggersShutdown.
+	CauseOnRunCompleted ShutdownCause = "onRun completed"
+)
+
+// ShutdownError is the stop cause of a bootstrap shut down by a shutdown event, see StopCause.
+type ShutdownError struct {
+	Cause  ShutdownCause
+	Reason string
+}
+
+func (e *ShutdownError) Error() string {
+	return "bootstrap: shut down: " + e.Reason
+}
+
+// causeOf returns the cause of the shutdown triggered with event.
+func causeOf(event shutdown.Event) ShutdownCause {
+	reason := event.Reason()
+	switch {
+	case strings.HasPrefix(reason, "received signal:"):
+		return CauseSignal
+	case reason == context.Canceled.Error(), reason == context.DeadlineExceeded.Error():
+		return CauseContextDone
+	default:
+		return ShutdownCause(reason)
+	}
+}
+
+// shutdownContext applies the shutdown timeout to ctx, replacing its deadline set by the
+// shutdown controller. The timeout configured for the cause of event comes first, then the one set
+// by SetShutdownTimeout, as of the beginning of the shutdown.
+// If a shutdown context func is configured, the returned context derives from its context instead,
+// with the deadline of ctx if no timeout applies.
+func (l *lifecycle) shutdownContext(ctx context.Context, event shutdown.Event) (context.Context, context.CancelFunc) {
+	l.timeoutOnce.Do(func() {
+		l.shutdownTimeout = time.Duration(l.b.state.shutdownTimeout.Load())
+	})
+	d, ok := l.b.causeTimeouts[causeOf(event)]
+	if !ok {
+		d = l.shutdownTimeout
+	}
+	if l.b.shutdownCtx != nil {
+		base := l.b.shutdownCtx()
+		if base == nil {
+			base = context.Background()
+		}
+		if d > 0 {
+			return context.WithTimeout(base, d)
+		}
+		if deadline, ok := ctx.Deadline(); ok {
+			return context.WithDeadline(base, deadline)
+		}
+		return context.WithCancel(base)
+	}
+	if d <= 0 {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(detach(ctx), d)
+}
+
+// SetShutdownTimeout sets the timeout of the shutdowns beginning after the call,
+// overriding the timeout of the shutdown controller. d <= 0 restores it.
+func (b bootstrap) SetShutdownTimeout(d time.Duration) {
+	if b.state == nil {
+		return
+	}
+	b.state.shutdownTimeout.Store(int64(d))
+}
+
+// StopCause returns why the last Run stopped.
+func (b bootstrap) StopCause() error {
+	if b.state == nil {
+		return nil
+	}
+	b.state.mux.Lock()
+	defer b.state.mux.Unlock()
+	return b.state.stopCause
+}
+
+// ShutdownEvent returns the first shutdown event of the last Run.
+func (b bootstrap) ShutdownEvent() shutdown.Event {
+	if b.state == nil {
+		return nil
+	}
+	b.state.mux.Lock()
+	defer b.state.mux.Unlock()
+	return b.state.event
+}
+
+// Controller returns the shutdown controller of the bootstrap, handling its shutdown triggers.
+// Callbacks added to it before Run are called on shutdown, along with the ones stopping the runners,
+// once per Run with its first shutdown event.
+func (b bootstrap) Controller() shutdown.Controller {
+	if b.state == nil {
+		return b.gs
+	}
+	return firstEventController{Controller: b.gs, state: b.state}
+}
+
+// firstEventController adds callbacks called with the first shutdown event of each Run only,
+// while several triggers may handle the shutdown.
+type firstEventController struct {
+	shutdown.Controller
+	state *runState
+}
+
+func (c firstEventController) AddShutdownCallback(callback shutdown.Callback) {
+	handled := &atomic.Int64{}
+	handled.Store(-1)
+	c.Controller.AddShutdownCallback(shutdown.CallbackFunc(func(ctx context.Context, event shutdown.Event) error {
+		run := c.state.runs.Load()
+		if handled.Swap(run) == run {
+			return nil
+		}
+		return callback.OnShutdown(ctx, event)
+	}))
+}
+
+func (s *runState) setShutdownEvent(event shutdown.Event) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	s.event = event
+}
+
+func (s *runState) setStopCause(err error) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	s.stopCause = err
+}
@@ -0,0 +1,338 @@
+package bootstrap
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/exp/slog"
+
+	"github.com/yimi-go/shutdown"
+)
+
+func Test_causeOf(t *testing.T) {
+	tests := map[string]ShutdownCause{
+		"received signal: terminated": CauseSignal,
+		"context canceled":            CauseContextDone,
+		"context deadline exceeded":   CauseContextDone,
+		"stop requested":              CauseStopRequested,
+		"memory pressure":             "memory pressure",
+	}
+	for reason, want := range tests {
+		reason := reason
+		assert.Equal(t, want, causeOf(shutdown.EventFunc(func() string {
+			return reason
+		})), reason)
+	}
+}
+
+func TestBootstrap_Run_causeTimeout(t *testing.T) {
+	run := func(t *testing.T, stop func(b Bootstrap, cancel context.CancelFunc)) time.Duration {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		r := NewMockRunner(ctrl)
+		r.EXPECT().Name().Return("testRunner").AnyTimes()
+		stopped := make(chan struct{})
+		r.EXPECT().Run(gomock.Any()).DoAndReturn(func(ctx context.Context) error {
+			<-stopped
+			return nil
+		})
+		var timeout time.Duration
+		r.EXPECT().Stop(gomock.Any()).DoAndReturn(func(ctx context.Context) error {
+			deadline, _ := ctx.Deadline()
+			timeout = time.Until(deadline)
+			close(stopped)
+			return nil
+		})
+		b := New(
+			WithRunners(r),
+			WithCauseTimeout(CauseStopRequested, time.Second*5),
+			WithCauseTimeout(CauseContextDone, time.Millisecond*100),
+		)
+		done := make(chan error)
+		go func() {
+			done <- b.Run(ctx)
+		}()
+		assert.Eventually(t, b.Ready, time.Second, time.Millisecond)
+		stop(b, cancel)
+		assert.Nil(t, <-done)
+		return timeout
+	}
+	t.Run("stop_requested", func(t *testing.T) {
+		timeout := run(t, func(b Bootstrap, _ context.CancelFunc) {
+			go func() {
+				_ = b.Stop(context.Background())
+			}()
+		})
+		assert.Greater(t, timeout, time.Second*4)
+	})
+	t.Run("context_done", func(t *testing.T) {
+		timeout := run(t, func(_ Bootstrap, cancel context.CancelFunc) {
+			cancel()
+		})
+		assert.LessOrEqual(t, timeout, time.Millisecond*100)
+	})
+}
+
+func TestBootstrap_SetShutdownTimeout(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	r := NewMockRunner(ctrl)
+	r.EXPECT().Name().Return("testRunner").AnyTimes()
+	stopped := make(chan struct{})
+	r.EXPECT().Run(gomock.Any()).DoAndReturn(func(ctx context.Context) error {
+		<-stopped
+		return nil
+	})
+	var timeout time.Duration
+	r.EXPECT().Stop(gomock.Any()).DoAndReturn(func(ctx context.Context) error {
+		deadline, _ := ctx.Deadline()
+		timeout = time.Until(deadline)
+		close(stopped)
+		return nil
+	})
+	b := New(WithRunners(r))
+	done := make(chan error)
+	go func() {
+		done <- b.Run(context.Background())
+	}()
+	assert.Eventually(t, b.Ready, time.Second, time.Millisecond)
+	b.SetShutdownTimeout(time.Second * 5)
+	assert.Nil(t, b.Stop(context.Background()))
+	assert.Nil(t, <-done)
+	assert.Greater(t, timeout, time.Second*4)
+
+	var nilState bootstrap
+	nilState.SetShutdownTimeout(time.Second)
+}
+
+func TestBootstrap_StopCause(t *testing.T) {
+	newRunner := func(ctrl *gomock.Controller, runErr error) *MockRunner {
+		r := NewMockRunner(ctrl)
+		r.EXPECT().Name().Return("testRunner").AnyTimes()
+		stopped := make(chan struct{})
+		r.EXPECT().Run(gomock.Any()).DoAndReturn(func(ctx context.Context) error {
+			if runErr != nil {
+				return runErr
+			}
+			<-stopped
+			return nil
+		})
+		r.EXPECT().Stop(gomock.Any()).DoAndReturn(func(ctx context.Context) error {
+			close(stopped)
+			return nil
+		}).AnyTimes()
+		return r
+	}
+	t.Run("signal", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		tr := NewMockTrigger(ctrl)
+		tr.EXPECT().Wait(gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, c shutdown.Controller) error {
+			c.HandleShutdown(ctx, shutdown.EventFunc(func() string {
+				return "received signal: terminated"
+			}))
+			return nil
+		})
+		b := New(WithRunners(newRunner(ctrl, nil)), WithTriggers(tr))
+		assert.Nil(t, b.StopCause())
+		assert.Nil(t, b.Run(context.Background()))
+		var shutdownErr *ShutdownError
+		if assert.ErrorAs(t, b.StopCause(), &shutdownErr) {
+			assert.Equal(t, CauseSignal, shutdownErr.Cause)
+			assert.Equal(t, "bootstrap: shut down: received signal: terminated", shutdownErr.Error())
+		}
+	})
+	t.Run("runner_error", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		runErr := errors.New("test")
+		b := New(WithRunners(newRunner(ctrl, runErr)))
+		assert.ErrorIs(t, b.Run(context.Background()), runErr)
+		assert.ErrorIs(t, b.StopCause(), runErr)
+	})
+	t.Run("context", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		ctx, cancel := context.WithCancelCause(context.Background())
+		cancelErr := errors.New("test")
+		b := New(WithRunners(newRunner(ctrl, nil)))
+		done := make(chan error)
+		go func() {
+			done <- b.Run(ctx)
+		}()
+		assert.Eventually(t, b.Ready, time.Second, time.Millisecond)
+		cancel(cancelErr)
+		assert.Nil(t, <-done)
+		assert.Same(t, cancelErr, b.StopCause())
+	})
+	t.Run("nil_state", func(t *testing.T) {
+		var b bootstrap
+		assert.Nil(t, b.StopCause())
+	})
+}
+
+func TestBootstrap_ShutdownEvent(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	logBuf := &bytes.Buffer{}
+	r := NewMockRunner(ctrl)
+	r.EXPECT().Name().Return("testRunner").AnyTimes()
+	r.EXPECT().Run(gomock.Any()).DoAndReturn(func(ctx context.Context) error {
+		<-ctx.Done()
+		return nil
+	})
+	r.EXPECT().Stop(gomock.Any()).Return(nil)
+	signal := func(reason string) *MockTrigger {
+		tr := NewMockTrigger(ctrl)
+		tr.EXPECT().Wait(gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, c shutdown.Controller) error {
+			c.HandleShutdown(ctx, shutdown.EventFunc(func() string {
+				return reason
+			}))
+			return nil
+		})
+		return tr
+	}
+	b := New(WithRunners(r), WithTriggers(signal("received signal: terminated")))
+	assert.Nil(t, b.ShutdownEvent())
+	assert.Nil(t, b.Run(bufLogCtx(context.Background(), logBuf)))
+	if assert.NotNil(t, b.ShutdownEvent()) {
+		assert.Equal(t, "received signal: terminated", b.ShutdownEvent().Reason())
+	}
+	mps := printAndJson(t, logBuf)
+	summary := mps[len(mps)-1]
+	assert.Equal(t, "bootstrap stopped.", summary[slog.MessageKey])
+	assert.Equal(t, "received signal: terminated", summary["reason"])
+}
+
+func TestBootstrap_Run_shutdownContext(t *testing.T) {
+	type key struct{}
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	r := NewMockRunner(ctrl)
+	r.EXPECT().Name().Return("testRunner").AnyTimes()
+	stopped := make(chan struct{})
+	r.EXPECT().Run(gomock.Any()).DoAndReturn(func(ctx context.Context) error {
+		<-stopped
+		return nil
+	})
+	var flushErr error
+	var deadline time.Duration
+	r.EXPECT().Stop(gomock.Any()).DoAndReturn(func(ctx context.Context) error {
+		defer close(stopped)
+		assert.Equal(t, "value", ctx.Value(key{}))
+		if d, ok := ctx.Deadline(); ok {
+			deadline = time.Until(d)
+		}
+		// A context respecting flush.
+		select {
+		case <-ctx.Done():
+			flushErr = ctx.Err()
+		case <-time.After(time.Millisecond * 10):
+		}
+		return nil
+	})
+	b := New(WithRunners(r), WithShutdownContext(func() context.Context {
+		return context.WithValue(context.Background(), key{}, "value")
+	}))
+	done := make(chan error)
+	go func() {
+		done <- b.Run(context.Background())
+	}()
+	assert.Eventually(t, b.Ready, time.Second, time.Millisecond)
+	stopCtx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.Nil(t, b.Stop(stopCtx))
+	assert.Nil(t, <-done)
+	assert.Nil(t, flushErr)
+	// The timeout of the shutdown controller still applies.
+	assert.Greater(t, deadline, time.Duration(0))
+	assert.LessOrEqual(t, deadline, time.Second)
+}
+
+func TestBootstrap_Controller(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	r := NewMockRunner(ctrl)
+	r.EXPECT().Name().Return("testRunner").AnyTimes()
+	stop := make(chan struct{})
+	r.EXPECT().Run(gomock.Any()).DoAndReturn(func(ctx context.Context) error {
+		<-stop
+		return nil
+	})
+	r.EXPECT().Stop(gomock.Any()).DoAndReturn(func(ctx context.Context) error {
+		close(stop)
+		return nil
+	})
+	b := New(WithRunners(r))
+	var mux sync.Mutex
+	var reasons []string
+	b.Controller().AddShutdownCallback(shutdown.CallbackFunc(func(ctx context.Context, event shutdown.Event) error {
+		mux.Lock()
+		defer mux.Unlock()
+		reasons = append(reasons, event.Reason())
+		return nil
+	}))
+	done := make(chan error)
+	go func() {
+		done <- b.Run(context.Background())
+	}()
+	assert.Eventually(t, b.Ready, time.Second, time.Millisecond)
+	assert.Nil(t, b.Stop(context.Background()))
+	assert.Nil(t, <-done)
+	mux.Lock()
+	defer mux.Unlock()
+	assert.Equal(t, []string{"stop requested"}, reasons)
+}
+
+func TestBootstrap_Run_shutdownTimeout(t *testing.T) {
+	t.Run("timeout", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		slow := NewMockRunner(ctrl)
+		slow.EXPECT().Name().Return("slow").AnyTimes()
+		slow.EXPECT().Run(gomock.Any()).DoAndReturn(func(ctx context.Context) error {
+			<-ctx.Done()
+			return nil
+		})
+		slow.EXPECT().Stop(gomock.Any()).DoAndReturn(func(ctx context.Context) error {
+			<-ctx.Done()
+			return ctx.Err()
+		})
+		b := New(WithRunners(slow), WithTimeouts(Timeouts{Shutdown: time.Millisecond * 20}), WithOnRun(func(ctx context.Context) error {
+			cancel()
+			return nil
+		}))
+		err := b.Run(ctx)
+		assert.ErrorIs(t, err, ErrShutdownTimeout)
+		assert.NotErrorIs(t, err, context.Canceled)
+		assert.ErrorContains(t, err, "runners stopping: slow")
+	})
+	t.Run("canceled", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		r := NewMockRunner(ctrl)
+		r.EXPECT().Name().Return("testRunner").AnyTimes()
+		r.EXPECT().Run(gomock.Any()).DoAndReturn(func(ctx context.Context) error {
+			<-ctx.Done()
+			return nil
+		})
+		r.EXPECT().Stop(gomock.Any()).Return(nil)
+		b := New(WithRunners(r), WithTimeouts(Timeouts{Shutdown: time.Millisecond * 20}), WithOnRun(func(ctx context.Context) error {
+			cancel()
+			return nil
+		}))
+		assert.Nil(t, b.Run(ctx))
+	})
+}
@@ -0,0 +1,71 @@
+package bootstrap
+
+import (
+	"time"
+)
+
+// Clock tells the time and waits for it, for the delays, backoffs and durations of the bootstrap.
+// See WithClock. Context deadlines, e.g. of the timeouts, use the time package.
+type Clock interface {
+	// Now returns the current time.
+	Now() time.Time
+	// After waits for d to elapse, then sends the current time on the returned channel.
+	After(d time.Duration) <-chan time.Time
+	// NewTimer creates a Timer sending the current time on its channel after d.
+	NewTimer(d time.Duration) Timer
+}
+
+// Timer is a timer of a Clock, see time.Timer.
+type Timer interface {
+	// C returns the channel the time is sent on when the timer fires.
+	C() <-chan time.Time
+	// Stop prevents the timer from firing. It returns false if it fired or was stopped already.
+	Stop() bool
+}
+
+// WithClock sets the clock of the bootstrap, e.g. a fake one in tests. The default is the real clock.
+func WithClock(c Clock) Option {
+	return func(b *bootstrap) {
+		b.clock = c
+	}
+}
+
+// realClock is the Clock of the time package.
+type realClock struct{}
+
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+func (realClock) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
+
+func (realClock) NewTimer(d time.Duration) Timer {
+	return realTimer{t: time.NewTimer(d)}
+}
+
+type realTimer struct {
+	t *time.Timer
+}
+
+func (t realTimer) C() <-chan time.Time {
+	return t.t.C
+}
+
+func (t realTimer) Stop() bool {
+	return t.t.Stop()
+}
+
+// clk returns the clock of b, the real one by default.
+func (b bootstrap) clk() Clock {
+	if b.clock == nil {
+		return realClock{}
+	}
+	return b.clock
+}
+
+// since returns the time elapsed since t on the clock of b.
+func (b bootstrap) since(t time.Time) time.Duration {
+	return b.clk().Now().Sub(t)
+}
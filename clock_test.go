@@ -0,0 +1,191 @@
+package bootstrap
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeClock is a Clock whose time only moves on advance.
+type fakeClock struct {
+	mux    sync.Mutex
+	now    time.Time
+	timers []*fakeTimer
+}
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{now: time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)}
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) After(d time.Duration) <-chan time.Time {
+	return c.NewTimer(d).C()
+}
+
+func (c *fakeClock) NewTimer(d time.Duration) Timer {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	t := &fakeTimer{c: make(chan time.Time, 1), at: c.now.Add(d)}
+	if d <= 0 {
+		t.fire(c.now)
+		return t
+	}
+	c.timers = append(c.timers, t)
+	return t
+}
+
+// waiting returns the number of timers not fired nor stopped.
+func (c *fakeClock) waiting() int {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	n := 0
+	for _, t := range c.timers {
+		if t.pending() {
+			n++
+		}
+	}
+	return n
+}
+
+// advance moves the time by d, firing the timers due.
+func (c *fakeClock) advance(d time.Duration) {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	c.now = c.now.Add(d)
+	for _, t := range c.timers {
+		if !t.at.After(c.now) {
+			t.fire(c.now)
+		}
+	}
+}
+
+type fakeTimer struct {
+	mux  sync.Mutex
+	c    chan time.Time
+	at   time.Time
+	done bool
+}
+
+func (t *fakeTimer) C() <-chan time.Time {
+	return t.c
+}
+
+func (t *fakeTimer) Stop() bool {
+	t.mux.Lock()
+	defer t.mux.Unlock()
+	if t.done {
+		return false
+	}
+	t.done = true
+	return true
+}
+
+func (t *fakeTimer) fire(now time.Time) {
+	t.mux.Lock()
+	defer t.mux.Unlock()
+	if t.done {
+		return
+	}
+	t.done = true
+	t.c <- now
+}
+
+func (t *fakeTimer) pending() bool {
+	t.mux.Lock()
+	defer t.mux.Unlock()
+	return !t.done
+}
+
+func TestWithClock(t *testing.T) {
+	b := bootstrap{}
+	assert.Equal(t, realClock{}, b.clk())
+	c := newFakeClock()
+	WithClock(c)(&b)
+	assert.Same(t, c, b.clk())
+}
+
+func Test_realClock(t *testing.T) {
+	c := realClock{}
+	assert.WithinDuration(t, time.Now(), c.Now(), time.Second)
+	<-c.After(time.Millisecond)
+	timer := c.NewTimer(time.Hour)
+	assert.True(t, timer.Stop())
+	timer = c.NewTimer(time.Millisecond)
+	<-timer.C()
+	assert.False(t, timer.Stop())
+}
+
+func TestBootstrap_Run_clock(t *testing.T) {
+	t.Run("ready_delay", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		r := NewMockRunner(ctrl)
+		r.EXPECT().Name().Return("testRunner").AnyTimes()
+		r.EXPECT().Run(gomock.Any()).DoAndReturn(func(ctx context.Context) error {
+			<-ctx.Done()
+			return nil
+		})
+		r.EXPECT().Stop(gomock.Any()).Return(nil)
+		c := newFakeClock()
+		b := New(WithRunners(r), WithReadyDelay(time.Hour), WithClock(c))
+		done := make(chan error)
+		go func() {
+			done <- b.Run(ctx)
+		}()
+		assert.Eventually(t, func() bool {
+			return c.waiting() == 1
+		}, time.Second, time.Millisecond)
+		assert.False(t, b.Ready())
+		c.advance(time.Minute)
+		assert.False(t, b.Ready())
+		c.advance(time.Hour)
+		assert.Eventually(t, b.Ready, time.Second, time.Millisecond)
+		c.advance(time.Minute)
+		assert.Equal(t, time.Hour+time.Minute*2, b.Uptime())
+		cancel()
+		assert.Nil(t, <-done)
+	})
+	t.Run("startup_deadline", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		r := readierRunner{MockRunner: NewMockRunner(ctrl), waitReady: func(ctx context.Context) error {
+			<-ctx.Done()
+			return ctx.Err()
+		}}
+		r.EXPECT().Name().Return("slow").AnyTimes()
+		r.EXPECT().Run(gomock.Any()).DoAndReturn(func(ctx context.Context) error {
+			<-ctx.Done()
+			return nil
+		})
+		r.EXPECT().Stop(gomock.Any()).Return(nil).AnyTimes()
+		c := newFakeClock()
+		done := make(chan error)
+		go func() {
+			done <- New(WithRunners(r), WithStartupDeadline(time.Hour), WithClock(c)).Run(context.Background())
+		}()
+		assert.Eventually(t, func() bool {
+			return c.waiting() == 1
+		}, time.Second, time.Millisecond)
+		select {
+		case err := <-done:
+			t.Fatalf("run returned before the deadline: %v", err)
+		case <-time.After(time.Millisecond * 20):
+		}
+		c.advance(time.Hour)
+		var timeoutErr *StartupTimeoutError
+		if assert.ErrorAs(t, <-done, &timeoutErr) {
+			assert.Equal(t, []string{"slow"}, timeoutErr.Pending)
+		}
+	})
+}
@@ -0,0 +1,55 @@
+package bootstrap
+
+import (
+	"context"
+	"time"
+)
+
+// detachedContext carries the values of its parent, but not its deadline or cancellation.
+type detachedContext struct {
+	parent context.Context
+}
+
+// detach returns a context carrying the values of parent, which is never done.
+func detach(parent context.Context) context.Context {
+	return detachedContext{parent: parent}
+}
+
+// detachKeepDeadline returns a cancellable context carrying the values and the deadline of parent,
+// but not its cancellation.
+func detachKeepDeadline(parent context.Context) (context.Context, context.CancelFunc) {
+	if d, ok := parent.Deadline(); ok {
+		return context.WithDeadline(detach(parent), d)
+	}
+	return context.WithCancel(detach(parent))
+}
+
+func (detachedContext) Deadline() (time.Time, bool) {
+	return time.Time{}, false
+}
+
+func (detachedContext) Done() <-chan struct{} {
+	return nil
+}
+
+func (detachedContext) Err() error {
+	return nil
+}
+
+func (c detachedContext) Value(key any) any {
+	return c.parent.Value(key)
+}
+
+// Context returns the context of the runners of the ongoing Run, done once they are to stop,
+// or nil outside of a Run.
+func (b bootstrap) Context() context.Context {
+	if b.state == nil {
+		return nil
+	}
+	b.state.mux.Lock()
+	defer b.state.mux.Unlock()
+	if b.state.current == nil {
+		return nil
+	}
+	return b.state.current.runnersCtx
+}
@@ -0,0 +1,74 @@
+package bootstrap
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_detach(t *testing.T) {
+	type key struct{}
+	parent, cancel := context.WithCancel(context.WithValue(context.Background(), key{}, "value"))
+	cancel()
+	ctx := detach(parent)
+	assert.Nil(t, ctx.Done())
+	assert.Nil(t, ctx.Err())
+	_, ok := ctx.Deadline()
+	assert.False(t, ok)
+	assert.Equal(t, "value", ctx.Value(key{}))
+}
+
+func Test_detachKeepDeadline(t *testing.T) {
+	parent, cancel := context.WithCancel(context.Background())
+	ctx, cancelCtx := detachKeepDeadline(parent)
+	cancel()
+	assert.Nil(t, ctx.Err())
+	_, ok := ctx.Deadline()
+	assert.False(t, ok)
+	cancelCtx()
+	assert.ErrorIs(t, ctx.Err(), context.Canceled)
+
+	deadline := time.Now().Add(time.Hour)
+	parent, cancel = context.WithDeadline(context.Background(), deadline)
+	ctx, cancelCtx = detachKeepDeadline(parent)
+	defer cancelCtx()
+	cancel()
+	assert.Nil(t, ctx.Err())
+	d, ok := ctx.Deadline()
+	assert.True(t, ok)
+	assert.Equal(t, deadline, d)
+}
+
+func TestBootstrap_Context(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	r := NewMockRunner(ctrl)
+	r.EXPECT().Name().Return("testRunner").AnyTimes()
+	r.EXPECT().Run(gomock.Any()).DoAndReturn(func(ctx context.Context) error {
+		<-ctx.Done()
+		return nil
+	})
+	r.EXPECT().Stop(gomock.Any()).Return(nil)
+	b := New(WithRunners(r))
+	assert.Nil(t, b.Context())
+	done := make(chan error)
+	go func() {
+		done <- b.Run(ctx)
+	}()
+	assert.Eventually(t, b.Ready, time.Second, time.Millisecond)
+	runCtx := b.Context()
+	if assert.NotNil(t, runCtx) {
+		assert.Nil(t, runCtx.Err())
+	}
+	cancel()
+	assert.Nil(t, <-done)
+	if runCtx != nil {
+		assert.NotNil(t, runCtx.Err())
+	}
+	assert.Nil(t, b.Context())
+}
@@ -0,0 +1,134 @@
+package bootstrap
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	"golang.org/x/exp/slog"
+)
+
+// checkDependencies returns an error wrapping ErrDependencyCycle if the dependencies form a cycle.
+func (b bootstrap) checkDependencies() error {
+	if cycle := findCycle(b.dependencies); cycle != nil {
+		return errors.WithMessage(ErrDependencyCycle, strings.Join(cycle, " -> "))
+	}
+	return nil
+}
+
+// sortEntries orders entries by dependency level, so that every runner comes after its
+// dependencies, then by priority, see Prioritizer, keeping the configured order among equals.
+// Equals are not ordered by name: runners of the same priority keep their registration order,
+// with or without dependencies. The dependencies must be acyclic.
+func (b bootstrap) sortEntries(entries []*runnerEntry) []*runnerEntry {
+	levels := map[string]int{}
+	var level func(name string) int
+	level = func(name string) int {
+		if lv, ok := levels[name]; ok {
+			return lv
+		}
+		lv := 0
+		for _, dep := range b.dependencies[name] {
+			if dl := level(dep) + 1; dl > lv {
+				lv = dl
+			}
+		}
+		levels[name] = lv
+		return lv
+	}
+	sorted := append([]*runnerEntry(nil), entries...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		li, lj := level(sorted[i].r.Name()), level(sorted[j].r.Name())
+		if li != lj {
+			return li < lj
+		}
+		return priorityOf(sorted[i].r) < priorityOf(sorted[j].r)
+	})
+	return sorted
+}
+
+// dependsOn reports whether the runner named name depends on the one named on, transitively.
+func (b bootstrap) dependsOn(name, on string) bool {
+	for _, dep := range b.dependencies[name] {
+		if dep == on || b.dependsOn(dep, on) {
+			return true
+		}
+	}
+	return false
+}
+
+// entry returns the first entry of the run set named name, or nil.
+func (l *lifecycle) entry(name string) *runnerEntry {
+	l.entriesMux.Lock()
+	defer l.entriesMux.Unlock()
+	for _, e := range l.entries {
+		if e.r.Name() == name {
+			return e
+		}
+	}
+	return nil
+}
+
+// awaitDependencies waits for the dependencies of e to be ready.
+func (l *lifecycle) awaitDependencies(e *runnerEntry) error {
+	name := e.r.Name()
+	for _, dep := range l.b.dependencies[name] {
+		de := l.entry(dep)
+		if de == nil {
+			if l.logger.Enabled(slog.WarnLevel) {
+				l.logger.Warn(fmt.Sprintf("Runner %s depends on %s, which is not found", name, dep))
+			}
+			l.b.warn(name, fmt.Sprintf("dependency %s not found", dep), nil)
+			continue
+		}
+		select {
+		case <-de.ready:
+		case <-l.egCtx.Done():
+			return l.egCtx.Err()
+		}
+		if !de.isReady.Load() {
+			return errors.Errorf("dependency %s of %s exited before ready", dep, name)
+		}
+	}
+	return nil
+}
+
+// awaitDependents waits for the runners depending on name to be stopped, or ctx to be done.
+func (l *lifecycle) awaitDependents(ctx context.Context, name string) {
+	for dependent, deps := range l.b.dependencies {
+		if !containsString(deps, name) {
+			continue
+		}
+		l.stopMux.Lock()
+		stopped, ok := l.stopRegistered[dependent]
+		l.stopMux.Unlock()
+		if !ok {
+			continue
+		}
+		select {
+		case <-stopped:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// markStopped records that the runner named name is stopped.
+func (l *lifecycle) markStopped(name string) {
+	l.stopMux.Lock()
+	defer l.stopMux.Unlock()
+	if stopped, ok := l.stopRegistered[name]; ok {
+		close(stopped)
+	}
+}
+
+func containsString(ss []string, s string) bool {
+	for _, v := range ss {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,131 @@
+package bootstrap
+
+import (
+	"context"
+	"io"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/exp/slog"
+
+	"github.com/yimi-go/runner"
+)
+
+func Test_bootstrap_checkDependencies(t *testing.T) {
+	b := bootstrap{}
+	assert.Nil(t, b.checkDependencies())
+	WithDependency("c", "b")(&b)
+	WithDependency("b", "a")(&b)
+	assert.Nil(t, b.checkDependencies())
+	WithDependency("a", "c")(&b)
+	err := b.checkDependencies()
+	assert.ErrorIs(t, err, ErrDependencyCycle)
+	assert.Contains(t, err.Error(), "a -> c -> b -> a")
+}
+
+func Test_bootstrap_sortEntries(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	l := &lifecycle{}
+	var entries []*runnerEntry
+	for _, name := range []string{"c", "x", "b", "a"} {
+		r := NewMockRunner(ctrl)
+		r.EXPECT().Name().Return(name).AnyTimes()
+		entries = append(entries, l.addEntry(r))
+	}
+	b := bootstrap{}
+	// Equals keep their registration order, not ordered by name.
+	assert.Equal(t, entries, b.sortEntries(entries))
+	WithDependency("c", "b")(&b)
+	WithDependency("b", "a")(&b)
+	var names []string
+	for _, e := range b.sortEntries(entries) {
+		names = append(names, e.r.Name())
+	}
+	assert.Equal(t, []string{"x", "a", "b", "c"}, names)
+}
+
+func Test_bootstrap_dependsOn(t *testing.T) {
+	b := bootstrap{}
+	WithDependency("c", "b")(&b)
+	WithDependency("b", "a")(&b)
+	assert.True(t, b.dependsOn("c", "b"))
+	assert.True(t, b.dependsOn("c", "a"))
+	assert.False(t, b.dependsOn("a", "c"))
+	assert.False(t, b.dependsOn("x", "a"))
+}
+
+func TestBootstrap_Run_dependencies(t *testing.T) {
+	t.Run("chain", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		var mux sync.Mutex
+		var started, stopped []string
+		var runners []runner.Runner
+		for _, name := range []string{"c", "b", "a"} {
+			name := name
+			ready := make(chan struct{})
+			r := readierRunner{MockRunner: NewMockRunner(ctrl), waitReady: func(ctx context.Context) error {
+				<-ready
+				return nil
+			}}
+			r.EXPECT().Name().Return(name).AnyTimes()
+			r.EXPECT().Run(gomock.Any()).DoAndReturn(func(ctx context.Context) error {
+				mux.Lock()
+				started = append(started, name)
+				mux.Unlock()
+				close(ready)
+				<-ctx.Done()
+				return nil
+			})
+			r.EXPECT().Stop(gomock.Any()).DoAndReturn(func(ctx context.Context) error {
+				mux.Lock()
+				stopped = append(stopped, name)
+				mux.Unlock()
+				return nil
+			})
+			runners = append(runners, r)
+		}
+		b := New(WithRunners(runners...), WithDependency("c", "b"), WithDependency("b", "a"))
+		done := make(chan error)
+		go func() {
+			done <- b.Run(ctx)
+		}()
+		assert.Eventually(t, b.Ready, time.Second, time.Millisecond)
+		cancel()
+		assert.Nil(t, <-done)
+		assert.Equal(t, []string{"a", "b", "c"}, started)
+		assert.Equal(t, []string{"c", "b", "a"}, stopped)
+	})
+	t.Run("cycle", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		a, b := NewMockRunner(ctrl), NewMockRunner(ctrl)
+		a.EXPECT().Name().Return("a").AnyTimes()
+		b.EXPECT().Name().Return("b").AnyTimes()
+		err := New(WithRunners(a, b), WithDependency("a", "b"), WithDependency("b", "a")).Run(context.Background())
+		assert.ErrorIs(t, err, ErrDependencyCycle)
+	})
+	t.Run("dependency_exited", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		a := readierRunner{MockRunner: NewMockRunner(ctrl), waitReady: func(ctx context.Context) error {
+			<-ctx.Done()
+			return ctx.Err()
+		}}
+		a.EXPECT().Name().Return("a").AnyTimes()
+		a.EXPECT().Run(gomock.Any()).Return(nil)
+		a.EXPECT().Stop(gomock.Any()).Return(nil).AnyTimes()
+		b := NewMockRunner(ctrl)
+		b.EXPECT().Name().Return("b").AnyTimes()
+		b.EXPECT().Stop(gomock.Any()).Return(nil).AnyTimes()
+		ctx := slog.NewContext(context.Background(), slog.New(slog.NewTextHandler(io.Discard)))
+		err := New(WithRunners(a, b), WithDependency("b", "a")).Run(ctx)
+		assert.ErrorContains(t, err, "dependency a of b exited before ready")
+	})
+}
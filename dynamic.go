@@ -0,0 +1,225 @@
+package bootstrap
+
+import (
+	"context"
+	"sync"
+
+	"github.com/pkg/errors"
+
+	"github.com/yimi-go/runner"
+	"github.com/yimi-go/shutdown"
+)
+
+// RunnerWindow defines when AddRunner accepts runners.
+//
+// All windows are open again once a Run has returned: the runners added then are kept, without
+// bound, and started by the next Run.
+type RunnerWindow int
+
+const (
+	// UntilRun accepts runners only while the bootstrap is not running.
+	// They are started with the others by the next Run.
+	UntilRun RunnerWindow = iota
+	// UntilStarted also accepts runners until the bootstrap has started.
+	// Runners added during startup are started right away, without holding the startup.
+	UntilStarted
+	// Always accepts runners until shutdown begins.
+	// Runners added while running are started right away and stopped on shutdown with the others.
+	Always
+)
+
+func (w RunnerWindow) String() string {
+	switch w {
+	case UntilRun:
+		return "until run"
+	case UntilStarted:
+		return "until started"
+	case Always:
+		return "always"
+	default:
+		return "unknown"
+	}
+}
+
+// accepts reports whether the window is open in phase p.
+func (w RunnerWindow) accepts(p phase) bool {
+	switch p {
+	case phaseIdle, phaseStopped:
+		return true
+	case phaseStarting:
+		return w == UntilStarted || w == Always
+	case phaseRunning:
+		return w == Always
+	default:
+		return false
+	}
+}
+
+// AddRunner adds r to the runners of the bootstrap, if the dynamic runner window is open.
+func (b bootstrap) AddRunner(r runner.Runner) error {
+	if isNilRunner(r) {
+		return ErrNilRunner
+	}
+	if b.state == nil {
+		return ErrRunnerRejected
+	}
+	s := b.state
+	s.mux.Lock()
+	if !b.runnerWindow.accepts(s.load()) {
+		s.mux.Unlock()
+		return ErrRunnerRejected
+	}
+	l := s.current
+	var e *runnerEntry
+	if l == nil {
+		// Rejected now, as it would fail every later Run.
+		if hasRunnerNamed(b.runners, r.Name()) || hasRunnerNamed(s.dynamic, r.Name()) {
+			s.mux.Unlock()
+			return errors.WithMessage(ErrDuplicateRunner, r.Name())
+		}
+	} else {
+		if l.hasRunner(r.Name()) {
+			s.mux.Unlock()
+			return errors.WithMessage(ErrDuplicateRunner, r.Name())
+		}
+		if e = l.addRunner(r); e == nil {
+			s.mux.Unlock()
+			return ErrRunnerRejected
+		}
+	}
+	s.dynamic = append(s.dynamic, r)
+	s.mux.Unlock()
+	// Started without s.mux, which the shutdown may be waiting for.
+	if e != nil {
+		l.startRunner(e, false)
+	}
+	return nil
+}
+
+// hasRunnerNamed reports whether a runner of rs is named name.
+func hasRunnerNamed(rs []runner.Runner, name string) bool {
+	for _, r := range rs {
+		if r.Name() == name {
+			return true
+		}
+	}
+	return false
+}
+
+// runnerSet returns the configured runners followed by the runners added by AddRunner.
+func (b bootstrap) runnerSet() []runner.Runner {
+	rs := append([]runner.Runner(nil), b.runners...)
+	if b.state == nil {
+		return rs
+	}
+	b.state.mux.Lock()
+	defer b.state.mux.Unlock()
+	return append(rs, b.state.dynamic...)
+}
+
+// activeRunners returns the run set of the ongoing Run, or the runner set if not running.
+func (b bootstrap) activeRunners() []runner.Runner {
+	if b.state == nil {
+		return b.runners
+	}
+	b.state.mux.Lock()
+	l := b.state.current
+	b.state.mux.Unlock()
+	if l == nil {
+		return b.runnerSet()
+	}
+	return l.runners()
+}
+
+// attach makes l the lifecycle of the ongoing Run and returns its initial run set,
+// the static runners followed by the runners added so far.
+// It returns an error, without attaching l, if runners of the set share a name.
+func (s *runState) attach(l *lifecycle, static []runner.Runner) ([]*runnerEntry, error) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	if err := checkRunnerSet(append(static[:len(static):len(static)], s.dynamic...)); err != nil {
+		return nil, err
+	}
+	s.current = l
+	entries := make([]*runnerEntry, 0, len(static)+len(s.dynamic))
+	for _, r := range static {
+		entries = append(entries, l.addEntry(r))
+	}
+	for _, r := range s.dynamic {
+		entries = append(entries, l.addEntry(r))
+	}
+	// Sized under s.mux, before runners are added while starting.
+	l.startErrs = make(chan error, len(entries))
+	return entries, nil
+}
+
+// detach clears l as the lifecycle of the ongoing Run, recording when it stopped.
+func (s *runState) detach(l *lifecycle) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	if s.current == l {
+		s.current = nil
+		stoppedAt := l.b.clk().Now()
+		s.stoppedAt.Store(&stoppedAt)
+	}
+}
+
+// addRunner integrates r, added while running, into the lifecycle: its stop callback is handed to
+// the dynamic stop callback, and the returned entry is to be started right away.
+// It returns nil if shutdown has begun.
+func (l *lifecycle) addRunner(r runner.Runner) *runnerEntry {
+	if l.b.state.load() >= phaseStopping {
+		return nil
+	}
+	l.dynamicMux.Lock()
+	defer l.dynamicMux.Unlock()
+	if l.dynamicClosed {
+		return nil
+	}
+	e := l.addEntry(r)
+	if l.reserveStop(r.Name()) {
+		l.dynamicStops = append(l.dynamicStops, l.stopCallback(r))
+	}
+	return e
+}
+
+// registerDynamicStop registers the shutdown callback stopping the runners added while running.
+// Registered up front, AddRunner needs not call the shutdown controller, which may be handling a
+// shutdown waiting for the bootstrap locks.
+func (l *lifecycle) registerDynamicStop() {
+	if l.dynamicStopped.Load() {
+		return
+	}
+	once := &sync.Once{}
+	l.b.gs.AddShutdownCallback(shutdown.CallbackFunc(func(ctx context.Context, event shutdown.Event) error {
+		if l.finished() {
+			return nil
+		}
+		var err error
+		once.Do(func() {
+			sCtx, cancel := l.shutdownContext(ctx, event)
+			l.beginShutdown(sCtx, event)
+			cancel()
+			l.dynamicMux.Lock()
+			l.dynamicClosed = true
+			stops := l.dynamicStops
+			l.dynamicMux.Unlock()
+			errs := make([]error, len(stops))
+			wg := sync.WaitGroup{}
+			for i, stop := range stops {
+				wg.Add(1)
+				go func(i int, stop shutdown.Callback) {
+					defer wg.Done()
+					errs[i] = stop.OnShutdown(ctx, event)
+				}(i, stop)
+			}
+			wg.Wait()
+			err = joinErrors(errs...)
+			l.dynamicStopped.Store(true)
+			if l.stoppedCount.Load() == l.stopTotal.Load() {
+				l.completeShutdown()
+			}
+		})
+		return err
+	}))
+}
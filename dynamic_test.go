@@ -0,0 +1,230 @@
+package bootstrap
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/yimi-go/shutdown"
+)
+
+func TestRunnerWindow_String(t *testing.T) {
+	assert.Equal(t, "until run", UntilRun.String())
+	assert.Equal(t, "until started", UntilStarted.String())
+	assert.Equal(t, "always", Always.String())
+	assert.Equal(t, "unknown", RunnerWindow(-1).String())
+}
+
+func TestRunnerWindow_accepts(t *testing.T) {
+	tests := []struct {
+		window RunnerWindow
+		want   map[phase]bool
+	}{
+		{
+			window: UntilRun,
+			want: map[phase]bool{
+				phaseIdle: true, phaseStarting: false, phaseRunning: false, phaseStopping: false, phaseStopped: true,
+			},
+		},
+		{
+			window: UntilStarted,
+			want: map[phase]bool{
+				phaseIdle: true, phaseStarting: true, phaseRunning: false, phaseStopping: false, phaseStopped: true,
+			},
+		},
+		{
+			window: Always,
+			want: map[phase]bool{
+				phaseIdle: true, phaseStarting: true, phaseRunning: true, phaseStopping: false, phaseStopped: true,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.window.String(), func(t *testing.T) {
+			for p, want := range tt.want {
+				assert.Equal(t, want, tt.window.accepts(p), "phase %d", p)
+			}
+		})
+	}
+}
+
+func TestBootstrap_AddRunner(t *testing.T) {
+	newBlockingRunner := func(ctrl *gomock.Controller, name string) (*MockRunner, chan struct{}) {
+		r := NewMockRunner(ctrl)
+		r.EXPECT().Name().Return(name).AnyTimes()
+		running := make(chan struct{})
+		r.EXPECT().Run(gomock.Any()).DoAndReturn(func(ctx context.Context) error {
+			close(running)
+			<-ctx.Done()
+			return nil
+		})
+		r.EXPECT().Stop(gomock.Any()).Return(nil)
+		return r, running
+	}
+	t.Run("until_run", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		r1, _ := newBlockingRunner(ctrl, "r1")
+		r2, running2 := newBlockingRunner(ctrl, "r2")
+		r3 := NewMockRunner(ctrl)
+		r3.EXPECT().Name().Return("r3").AnyTimes()
+		b := New(WithRunners(r1))
+		assert.Nil(t, b.AddRunner(r2))
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan error)
+		go func() {
+			done <- b.Run(ctx)
+		}()
+		assert.Eventually(t, b.Ready, time.Second, time.Millisecond)
+		<-running2
+		assert.ErrorIs(t, b.AddRunner(r3), ErrRunnerRejected)
+		cancel()
+		assert.Nil(t, <-done)
+		assert.Nil(t, b.AddRunner(r3))
+	})
+	t.Run("until_started", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		r1, _ := newBlockingRunner(ctrl, "r1")
+		r2, running2 := newBlockingRunner(ctrl, "r2")
+		r3 := NewMockRunner(ctrl)
+		r3.EXPECT().Name().Return("r3").AnyTimes()
+		var b Bootstrap
+		b = New(WithRunners(r1), WithDynamicRunnerWindow(UntilStarted), WithBeforeRun(func(ctx context.Context) error {
+			return b.AddRunner(r2)
+		}))
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan error)
+		go func() {
+			done <- b.Run(ctx)
+		}()
+		assert.Eventually(t, b.Ready, time.Second, time.Millisecond)
+		<-running2
+		assert.ErrorIs(t, b.AddRunner(r3), ErrRunnerRejected)
+		cancel()
+		assert.Nil(t, <-done)
+	})
+	t.Run("always", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		r2, running2 := newBlockingRunner(ctrl, "r2")
+		r3 := NewMockRunner(ctrl)
+		r3.EXPECT().Name().Return("r3").AnyTimes()
+		var b Bootstrap
+		r1 := NewMockRunner(ctrl)
+		r1.EXPECT().Name().Return("r1").AnyTimes()
+		r1.EXPECT().Run(gomock.Any()).DoAndReturn(func(ctx context.Context) error {
+			<-ctx.Done()
+			return nil
+		})
+		r1.EXPECT().Stop(gomock.Any()).DoAndReturn(func(ctx context.Context) error {
+			assert.ErrorIs(t, b.AddRunner(r3), ErrRunnerRejected)
+			return nil
+		})
+		b = New(WithRunners(r1), WithDynamicRunnerWindow(Always))
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan error)
+		go func() {
+			done <- b.Run(ctx)
+		}()
+		assert.Eventually(t, b.Ready, time.Second, time.Millisecond)
+		assert.Nil(t, b.AddRunner(r2))
+		<-running2
+		cancel()
+		assert.Nil(t, <-done)
+	})
+	t.Run("duplicate_name", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		r1, _ := newBlockingRunner(ctrl, "r1")
+		dup := NewMockRunner(ctrl)
+		dup.EXPECT().Name().Return("r1").AnyTimes()
+		b := New(WithRunners(r1), WithDynamicRunnerWindow(Always))
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan error)
+		go func() {
+			done <- b.Run(ctx)
+		}()
+		assert.Eventually(t, b.Ready, time.Second, time.Millisecond)
+		assert.ErrorIs(t, b.AddRunner(dup), ErrDuplicateRunner)
+		cancel()
+		assert.Nil(t, <-done)
+		// Not running, rejected too, against the configured runners and the added ones.
+		assert.ErrorIs(t, b.AddRunner(dup), ErrDuplicateRunner)
+		added := NewMockRunner(ctrl)
+		added.EXPECT().Name().Return("added").AnyTimes()
+		assert.Nil(t, b.AddRunner(added))
+		assert.ErrorIs(t, b.AddRunner(added), ErrDuplicateRunner)
+	})
+	t.Run("during_shutdown", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		var runs, stops atomic.Int32
+		newRunner := func(name string) *MockRunner {
+			r := NewMockRunner(ctrl)
+			r.EXPECT().Name().Return(name).AnyTimes()
+			r.EXPECT().Run(gomock.Any()).DoAndReturn(func(ctx context.Context) error {
+				runs.Add(1)
+				<-ctx.Done()
+				return nil
+			}).MaxTimes(1)
+			r.EXPECT().Stop(gomock.Any()).DoAndReturn(func(ctx context.Context) error {
+				stops.Add(1)
+				return nil
+			}).MaxTimes(1)
+			return r
+		}
+		for i := 0; i < 20; i++ {
+			runs.Store(0)
+			stops.Store(0)
+			b := New(WithRunners(newRunner("static")), WithDynamicRunnerWindow(Always)).(bootstrap)
+			done := make(chan error, 1)
+			go func() {
+				done <- b.Run(context.Background())
+			}()
+			assert.Eventually(t, b.Ready, time.Second, time.Millisecond)
+			wg := sync.WaitGroup{}
+			for j := 0; j < 4; j++ {
+				wg.Add(1)
+				go func(j int) {
+					defer wg.Done()
+					for k := 0; ; k++ {
+						// The window reopens once Run returns, so stop adding once shutdown has begun.
+						if b.AddRunner(newRunner(fmt.Sprintf("dynamic-%d-%d", j, k))) != nil ||
+							b.state.load() >= phaseStopping {
+							return
+						}
+					}
+				}(j)
+			}
+			b.Controller().HandleShutdown(context.Background(), shutdown.EventFunc(func() string {
+				return "test"
+			}))
+			select {
+			case <-done:
+			case <-time.After(time.Second * 5):
+				t.Fatal("run did not return")
+			}
+			wg.Wait()
+			assert.Equal(t, runs.Load(), stops.Load())
+		}
+	})
+	t.Run("nil_runner", func(t *testing.T) {
+		var nilRunner *MockRunner
+		b := New()
+		assert.ErrorIs(t, b.AddRunner(nil), ErrNilRunner)
+		assert.ErrorIs(t, b.AddRunner(nilRunner), ErrNilRunner)
+	})
+	t.Run("nil_state", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		var b bootstrap
+		assert.ErrorIs(t, b.AddRunner(NewMockRunner(ctrl)), ErrRunnerRejected)
+	})
+}
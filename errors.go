@@ -0,0 +1,182 @@
+package bootstrap
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrUnstoppable is returned by Run in strict mode if nothing could ever stop it.
+// See WithStrictShutdownTriggers.
+var ErrUnstoppable = errors.New("bootstrap: run context is not cancellable and no shutdown trigger is configured")
+
+// ErrNotRunning is returned by Stop if the bootstrap is not running.
+var ErrNotRunning = errors.New("bootstrap: not running")
+
+// ErrStoppedBeforeStart is returned by Run if Stop was called before the runners started.
+var ErrStoppedBeforeStart = errors.New("bootstrap: stopped before start")
+
+// ErrRunnerRejected is returned by AddRunner outside of the dynamic runner window.
+// See WithDynamicRunnerWindow.
+var ErrRunnerRejected = errors.New("bootstrap: runner rejected, outside of the dynamic runner window")
+
+// ErrDependencyCycle is returned by Run if the runner dependencies form a cycle.
+// See WithDependency.
+var ErrDependencyCycle = errors.New("bootstrap: runner dependency cycle")
+
+// ErrRunnerTimeout is the error of a runner which ran longer than its timeout.
+// See WithRunnerTimeout.
+var ErrRunnerTimeout = errors.New("bootstrap: runner timed out")
+
+// ErrNoRunners is reported by Validate if the bootstrap has no runners, and no job to run instead.
+var ErrNoRunners = errors.New("bootstrap: no runners")
+
+// ErrNilRunner is returned by AddRunner if the runner is nil, and by Run if a start hook returns one.
+var ErrNilRunner = errors.New("bootstrap: nil runner")
+
+// ErrDuplicateRunner is reported by Validate if runners share a name.
+var ErrDuplicateRunner = errors.New("bootstrap: duplicate runner name")
+
+// ErrRunTimeout is returned by Run if it was shut down by its timeout. See WithRunTimeout.
+var ErrRunTimeout = errors.New("bootstrap: run timeout exceeded")
+
+// ErrStopOrderConflict is returned by Run if the stop order stops a runner before one depending on it.
+// See WithStopOrder.
+var ErrStopOrderConflict = errors.New("bootstrap: stop order conflicts with runner dependencies")
+
+// ErrOrderConflict is returned by Run if the dependencies, shutdown phases, priorities and stop
+// order of the runners contradict each other, which would hold the shutdown until it times out.
+var ErrOrderConflict = errors.New("bootstrap: runner ordering conflict")
+
+// ErrShutdownTimeout is returned by Run if runners were still stopping when the shutdown timed out.
+// Unlike context.Canceled, it tells the shutdown was not complete.
+var ErrShutdownTimeout = errors.New("bootstrap: shutdown timed out")
+
+// ErrDone is returned by onRun once its work is done, to shut the bootstrap down gracefully.
+// Run then returns nil, unless the shutdown fails.
+var ErrDone = errors.New("bootstrap: done")
+
+// BootstrapError is returned by Run if a runner or onRun failed.
+type BootstrapError struct {
+	// Err is the failure.
+	Err error
+	// Started lists, in registration order, the names of the runners that had started successfully,
+	// i.e. runners that callers may need to clean up.
+	Started []string
+}
+
+func (e *BootstrapError) Error() string {
+	return "bootstrap run err: " + e.Err.Error()
+}
+
+func (e *BootstrapError) Unwrap() error {
+	return e.Err
+}
+
+// PhaseError is returned by Run if its context is done before a startup phase finished.
+type PhaseError struct {
+	// Phase is the name of the unfinished phase.
+	Phase string
+	// Err is the error of the Run context.
+	Err error
+}
+
+func (e *PhaseError) Error() string {
+	return fmt.Sprintf("bootstrap: %s phase not finished: %v", e.Phase, e.Err)
+}
+
+func (e *PhaseError) Unwrap() error {
+	return e.Err
+}
+
+// StartupTimeoutError is returned by Run if runners did not signal their start before the startup
+// deadline. See WithStartupDeadline.
+type StartupTimeoutError struct {
+	Deadline time.Duration
+	// Pending lists the names of the runners which did not signal their start.
+	Pending []string
+}
+
+func (e *StartupTimeoutError) Error() string {
+	return fmt.Sprintf("bootstrap: startup deadline %s exceeded, runners not started: %s",
+		e.Deadline, strings.Join(e.Pending, ", "))
+}
+
+// The phases of a RunnerError.
+const (
+	// RunnerPhaseStart is the startup of a runner, until it is ready.
+	RunnerPhaseStart = "start"
+	// RunnerPhaseRun is the run of a runner, i.e. its Run method.
+	RunnerPhaseRun = "run"
+	// RunnerPhaseStop is the stop of a runner, i.e. its Stop method.
+	RunnerPhaseStop = "stop"
+)
+
+// RunnerError is the failure of a runner during a phase.
+type RunnerError struct {
+	// Name is the name of the runner.
+	Name string
+	// Phase is one of RunnerPhaseStart, RunnerPhaseRun and RunnerPhaseStop.
+	Phase string
+	Err   error
+}
+
+func (e *RunnerError) Error() string {
+	var verb string
+	switch e.Phase {
+	case RunnerPhaseStart:
+		verb = "starting"
+	case RunnerPhaseRun:
+		verb = "running"
+	case RunnerPhaseStop:
+		verb = "stopping"
+	default:
+		verb = e.Phase
+	}
+	return fmt.Sprintf("%s %s failed: %v", verb, e.Name, e.Err)
+}
+
+func (e *RunnerError) Unwrap() error {
+	return e.Err
+}
+
+// errCollector gathers errors reported concurrently, e.g. by shutdown callbacks.
+type errCollector struct {
+	mux  sync.Mutex
+	errs []error
+}
+
+func (c *errCollector) add(err error) {
+	if err == nil {
+		return
+	}
+	c.mux.Lock()
+	c.errs = append(c.errs, err)
+	c.mux.Unlock()
+}
+
+func (c *errCollector) err() error {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	return errors.Join(c.errs...)
+}
+
+// joinErrors joins the non-nil errs. Unlike errors.Join, a single error is returned as is.
+func joinErrors(errs ...error) error {
+	var nonNil []error
+	for _, err := range errs {
+		if err != nil {
+			nonNil = append(nonNil, err)
+		}
+	}
+	switch len(nonNil) {
+	case 0:
+		return nil
+	case 1:
+		return nonNil[0]
+	default:
+		return errors.Join(nonNil...)
+	}
+}
@@ -0,0 +1,88 @@
+package bootstrap
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/yimi-go/runner"
+)
+
+func Test_joinErrors(t *testing.T) {
+	err1, err2 := errors.New("test1"), errors.New("test2")
+	assert.Nil(t, joinErrors())
+	assert.Nil(t, joinErrors(nil, nil))
+	assert.Same(t, err1, joinErrors(nil, err1))
+	joined := joinErrors(err1, nil, err2)
+	assert.ErrorIs(t, joined, err1)
+	assert.ErrorIs(t, joined, err2)
+}
+
+func Test_errCollector(t *testing.T) {
+	c := &errCollector{}
+	assert.Nil(t, c.err())
+	c.add(nil)
+	assert.Nil(t, c.err())
+	err := errors.New("test")
+	c.add(err)
+	assert.ErrorIs(t, c.err(), err)
+}
+
+func TestBootstrapError(t *testing.T) {
+	t.Run("error", func(t *testing.T) {
+		err := errors.New("test")
+		bErr := &BootstrapError{Err: err}
+		assert.Equal(t, "bootstrap run err: test", bErr.Error())
+		assert.ErrorIs(t, bErr, err)
+	})
+	t.Run("started", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		var rs []runner.Runner
+		for _, name := range []string{"a", "b"} {
+			r := NewMockRunner(ctrl)
+			r.EXPECT().Name().Return(name).AnyTimes()
+			r.EXPECT().Run(gomock.Any()).DoAndReturn(func(ctx context.Context) error {
+				<-ctx.Done()
+				return nil
+			})
+			r.EXPECT().Stop(gomock.Any()).Return(nil)
+			rs = append(rs, r)
+		}
+		failing := NewMockRunner(ctrl)
+		failing.EXPECT().Name().Return("c").AnyTimes()
+		runErr := errors.New("test")
+		failing.EXPECT().Run(gomock.Any()).Return(runErr)
+		failing.EXPECT().Stop(gomock.Any()).Return(nil)
+		rs = append(rs, failing)
+		b := New(WithRunners(rs...))
+		err := b.Run(context.Background())
+		bErr := &BootstrapError{}
+		if assert.ErrorAs(t, err, &bErr) {
+			assert.Equal(t, []string{"a", "b"}, bErr.Started)
+			assert.ErrorIs(t, err, runErr)
+		}
+		var runnerErr *RunnerError
+		if assert.ErrorAs(t, err, &runnerErr) {
+			assert.Equal(t, "c", runnerErr.Name)
+			assert.Equal(t, RunnerPhaseRun, runnerErr.Phase)
+		}
+	})
+}
+
+func TestRunnerError(t *testing.T) {
+	err := errors.New("test")
+	for phase, want := range map[string]string{
+		RunnerPhaseStart: "starting r failed: test",
+		RunnerPhaseRun:   "running r failed: test",
+		RunnerPhaseStop:  "stopping r failed: test",
+		"reload":         "reload r failed: test",
+	} {
+		rErr := &RunnerError{Name: "r", Phase: phase, Err: err}
+		assert.Equal(t, want, rErr.Error())
+		assert.ErrorIs(t, rErr, err)
+	}
+}
@@ -0,0 +1,29 @@
+package bootstrap
+
+import (
+	"context"
+)
+
+// awaitLowerPhases waits for the runners of the shutdown phases lower than the one of the runner
+// named name to be stopped, or ctx to be done. See WithRunnerGroup.
+func (l *lifecycle) awaitLowerPhases(ctx context.Context, name string) {
+	if len(l.b.phases) == 0 {
+		return
+	}
+	phase := l.b.phases[name]
+	l.stopMux.Lock()
+	var waits []chan struct{}
+	for other, stopped := range l.stopRegistered {
+		if l.b.phases[other] < phase {
+			waits = append(waits, stopped)
+		}
+	}
+	l.stopMux.Unlock()
+	for _, stopped := range waits {
+		select {
+		case <-stopped:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package bootstrap
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/yimi-go/runner"
+	"golang.org/x/sync/errgroup"
+)
+
+// DefaultGroupStopTimeout bounds the stop of the runners of a group, when one of them failed.
+const DefaultGroupStopTimeout = time.Second
+
+type groupRunner struct {
+	name        string
+	runners     []runner.Runner
+	stopTimeout time.Duration
+
+	mux  sync.Mutex
+	stop *groupStop
+}
+
+// groupStop is the stop of the runners of a group for one Run, done once.
+type groupStop struct {
+	once sync.Once
+	err  error
+	// ran is set once the Run it belongs to returned, under the mutex of the group.
+	ran bool
+}
+
+// NewGroupRunner creates a runner.Runner named name, running rs together: its Run runs them
+// concurrently, and its Stop stops them in reverse order. If one of them fails, the others are
+// stopped, within DefaultGroupStopTimeout, and Run returns the failure.
+func NewGroupRunner(name string, rs ...runner.Runner) runner.Runner {
+	return NewGroupRunnerWithStopTimeout(name, DefaultGroupStopTimeout, rs...)
+}
+
+// NewGroupRunnerWithStopTimeout is like NewGroupRunner, but the others are stopped within
+// stopTimeout if one of them fails.
+func NewGroupRunnerWithStopTimeout(name string, stopTimeout time.Duration, rs ...runner.Runner) runner.Runner {
+	return &groupRunner{name: name, runners: rs, stopTimeout: stopTimeout}
+}
+
+func (g *groupRunner) Name() string {
+	return g.name
+}
+
+func (g *groupRunner) Run(ctx context.Context) error {
+	// Each Run is stopped once, the stop called before it included.
+	g.mux.Lock()
+	if g.stop == nil || g.stop.ran {
+		g.stop = &groupStop{}
+	}
+	s := g.stop
+	g.mux.Unlock()
+	defer func() {
+		g.mux.Lock()
+		s.ran = true
+		g.mux.Unlock()
+	}()
+	eg, egCtx := errgroup.WithContext(ctx)
+	for _, r := range g.runners {
+		r := r
+		eg.Go(func() error {
+			if err := r.Run(egCtx); err != nil {
+				// Stop the others, bounded as the bootstrap may stop the group meanwhile and wait for it.
+				go func() {
+					stopCtx, cancel := context.WithTimeout(detach(ctx), g.stopTimeout)
+					defer cancel()
+					_ = g.stopRunners(stopCtx, s)
+				}()
+				return errors.WithMessagef(err, "run %s of group %s failed", r.Name(), g.name)
+			}
+			return nil
+		})
+	}
+	return eg.Wait()
+}
+
+func (g *groupRunner) Stop(ctx context.Context) error {
+	g.mux.Lock()
+	if g.stop == nil {
+		g.stop = &groupStop{}
+	}
+	s := g.stop
+	g.mux.Unlock()
+	return g.stopRunners(ctx, s)
+}
+
+// stopRunners stops the runners in reverse order, once for s.
+func (g *groupRunner) stopRunners(ctx context.Context, s *groupStop) error {
+	s.once.Do(func() {
+		errs := make([]error, 0, len(g.runners))
+		for i := len(g.runners) - 1; i >= 0; i-- {
+			r := g.runners[i]
+			if err := r.Stop(ctx); err != nil {
+				errs = append(errs, errors.WithMessagef(err, "stop %s of group %s failed", r.Name(), g.name))
+			}
+		}
+		s.err = joinErrors(errs...)
+	})
+	return s.err
+}
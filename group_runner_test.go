@@ -0,0 +1,116 @@
+package bootstrap
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+func newGroupChild(ctrl *gomock.Controller, name string, stops *[]string) *MockRunner {
+	r := NewMockRunner(ctrl)
+	r.EXPECT().Name().Return(name).AnyTimes()
+	stop := make(chan struct{})
+	r.EXPECT().Run(gomock.Any()).DoAndReturn(func(ctx context.Context) error {
+		<-stop
+		return nil
+	})
+	r.EXPECT().Stop(gomock.Any()).DoAndReturn(func(ctx context.Context) error {
+		*stops = append(*stops, name)
+		close(stop)
+		return nil
+	})
+	return r
+}
+
+func TestNewGroupRunner(t *testing.T) {
+	t.Run("stop", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		var stops []string
+		g := NewGroupRunner("group",
+			newGroupChild(ctrl, "a", &stops), newGroupChild(ctrl, "b", &stops))
+		assert.Equal(t, "group", g.Name())
+		done := make(chan error)
+		go func() {
+			done <- g.Run(context.Background())
+		}()
+		assert.Nil(t, g.Stop(context.Background()))
+		assert.Nil(t, <-done)
+		assert.Equal(t, []string{"b", "a"}, stops)
+		// Stopped once.
+		assert.Nil(t, g.Stop(context.Background()))
+	})
+	t.Run("failed", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		var stops []string
+		runErr := errors.New("test")
+		failing := NewMockRunner(ctrl)
+		failing.EXPECT().Name().Return("failing").AnyTimes()
+		failing.EXPECT().Run(gomock.Any()).Return(runErr)
+		stopErr := errors.New("stop")
+		failing.EXPECT().Stop(gomock.Any()).Return(stopErr)
+		g := NewGroupRunner("group", newGroupChild(ctrl, "a", &stops), failing)
+		err := g.Run(context.Background())
+		assert.ErrorIs(t, err, runErr)
+		assert.Contains(t, err.Error(), "run failing of group group failed")
+		assert.Equal(t, []string{"a"}, stops)
+		err = g.Stop(context.Background())
+		assert.ErrorIs(t, err, stopErr)
+		assert.Contains(t, err.Error(), "stop failing of group group failed")
+	})
+	t.Run("failed_stop_timeout", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		failing := NewMockRunner(ctrl)
+		failing.EXPECT().Name().Return("failing").AnyTimes()
+		failing.EXPECT().Run(gomock.Any()).Return(errors.New("test"))
+		failing.EXPECT().Stop(gomock.Any()).Return(nil)
+		hung := NewMockRunner(ctrl)
+		hung.EXPECT().Name().Return("hung").AnyTimes()
+		stopped := make(chan struct{})
+		hung.EXPECT().Run(gomock.Any()).DoAndReturn(func(ctx context.Context) error {
+			<-stopped
+			return nil
+		})
+		hung.EXPECT().Stop(gomock.Any()).DoAndReturn(func(ctx context.Context) error {
+			// Returns once the stop times out only.
+			<-ctx.Done()
+			close(stopped)
+			return ctx.Err()
+		})
+		g := NewGroupRunnerWithStopTimeout("group", time.Millisecond*10, hung, failing)
+		assert.Error(t, g.Run(context.Background()))
+		assert.ErrorIs(t, g.Stop(context.Background()), context.DeadlineExceeded)
+	})
+	t.Run("rerun", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		r := NewMockRunner(ctrl)
+		r.EXPECT().Name().Return("a").AnyTimes()
+		running, stop := make(chan struct{}), make(chan struct{})
+		r.EXPECT().Run(gomock.Any()).DoAndReturn(func(ctx context.Context) error {
+			running <- struct{}{}
+			<-stop
+			return nil
+		}).Times(2)
+		r.EXPECT().Stop(gomock.Any()).DoAndReturn(func(ctx context.Context) error {
+			stop <- struct{}{}
+			return nil
+		}).Times(2)
+		g := NewGroupRunner("group", r)
+		for i := 0; i < 2; i++ {
+			done := make(chan error)
+			go func() {
+				done <- g.Run(context.Background())
+			}()
+			<-running
+			assert.Nil(t, g.Stop(context.Background()))
+			assert.Nil(t, <-done)
+		}
+	})
+}
@@ -0,0 +1,52 @@
+package bootstrap
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBootstrap_Run_runnerGroup(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var mux sync.Mutex
+	var events []string
+	newRunner := func(name string, stopDelay time.Duration) *MockRunner {
+		r := NewMockRunner(ctrl)
+		r.EXPECT().Name().Return(name).AnyTimes()
+		r.EXPECT().Run(gomock.Any()).DoAndReturn(func(ctx context.Context) error {
+			<-ctx.Done()
+			return nil
+		})
+		r.EXPECT().Stop(gomock.Any()).DoAndReturn(func(ctx context.Context) error {
+			mux.Lock()
+			events = append(events, "stop "+name)
+			mux.Unlock()
+			time.Sleep(stopDelay)
+			mux.Lock()
+			events = append(events, "stopped "+name)
+			mux.Unlock()
+			return nil
+		})
+		return r
+	}
+	api1, api2 := newRunner("api1", time.Millisecond*20), newRunner("api2", time.Millisecond*10)
+	worker := newRunner("worker", 0)
+	b := New(WithRunnerGroup(2, worker), WithRunnerGroup(1, api1, api2))
+	done := make(chan error)
+	go func() {
+		done <- b.Run(ctx)
+	}()
+	assert.Eventually(t, b.Ready, time.Second, time.Millisecond)
+	cancel()
+	assert.Nil(t, <-done)
+	assert.Len(t, events, 6)
+	assert.ElementsMatch(t, []string{"stop api1", "stop api2", "stopped api1", "stopped api2"}, events[:4])
+	assert.Equal(t, []string{"stop worker", "stopped worker"}, events[4:])
+}
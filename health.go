@@ -0,0 +1,29 @@
+package bootstrap
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+)
+
+// HealthChecker is an optional interface a runner.Runner may implement to report its health.
+type HealthChecker interface {
+	// HealthCheck returns a non-nil error if the runner is unhealthy.
+	HealthCheck(ctx context.Context) error
+}
+
+// Health checks all runners implementing HealthChecker and joins their errors.
+// Runners not implementing HealthChecker are treated as healthy.
+func (b bootstrap) Health(ctx context.Context) error {
+	var errs []error
+	for _, r := range b.activeRunners() {
+		hc, ok := r.(HealthChecker)
+		if !ok {
+			continue
+		}
+		if err := hc.HealthCheck(ctx); err != nil {
+			errs = append(errs, errors.WithMessagef(err, "runner %s unhealthy", r.Name()))
+		}
+	}
+	return joinErrors(errs...)
+}
@@ -0,0 +1,65 @@
+package bootstrap
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/pkg/errors"
+
+	"github.com/yimi-go/runner"
+)
+
+const (
+	// HealthPath is the liveness endpoint served by the health runner.
+	HealthPath = "/healthz"
+	// ReadyPath is the readiness endpoint served by the health runner.
+	ReadyPath = "/readyz"
+)
+
+type healthRunner struct {
+	srv *http.Server
+}
+
+// NewHealthRunner creates a runner.Runner serving liveness on HealthPath and readiness on ReadyPath
+// of addr, backed by Health and Ready of b. Register it with WithRunners.
+func NewHealthRunner(addr string, b Bootstrap) runner.Runner {
+	mux := http.NewServeMux()
+	mux.HandleFunc(HealthPath, func(w http.ResponseWriter, req *http.Request) {
+		if err := b.Health(req.Context()); err != nil {
+			http.Error(w, err.Error(), http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = w.Write([]byte("ok"))
+	})
+	mux.Handle(ReadyPath, readinessHandler{b: b})
+	return &healthRunner{srv: &http.Server{Addr: addr, Handler: mux}}
+}
+
+// readinessHandler reports the readiness of b, see Bootstrap.Ready.
+type readinessHandler struct {
+	b Bootstrap
+}
+
+func (h readinessHandler) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	if h.b == nil || !h.b.Ready() {
+		http.Error(w, "not ready", http.StatusServiceUnavailable)
+		return
+	}
+	_, _ = w.Write([]byte("ok"))
+}
+
+func (h *healthRunner) Name() string {
+	return "health"
+}
+
+func (h *healthRunner) Run(_ context.Context) error {
+	err := h.srv.ListenAndServe()
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+	return err
+}
+
+func (h *healthRunner) Stop(ctx context.Context) error {
+	return h.srv.Shutdown(ctx)
+}
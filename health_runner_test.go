@@ -0,0 +1,94 @@
+package bootstrap
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewHealthRunner(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	hc := &healthCheckRunner{MockRunner: NewMockRunner(ctrl)}
+	hc.EXPECT().Name().Return("testRunner").AnyTimes()
+	b := New(WithRunners(hc)).(bootstrap)
+	hr := NewHealthRunner(":0", b)
+	assert.Equal(t, "health", hr.Name())
+	srv := httptest.NewServer(hr.(*healthRunner).srv.Handler)
+	defer srv.Close()
+	get := func(path string) int {
+		resp, err := http.Get(srv.URL + path)
+		if !assert.Nil(t, err) {
+			return 0
+		}
+		_ = resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	assert.Equal(t, http.StatusOK, get(HealthPath))
+	assert.Equal(t, http.StatusServiceUnavailable, get(ReadyPath))
+
+	b.state.store(phaseRunning)
+	assert.Equal(t, http.StatusOK, get(ReadyPath))
+
+	hc.err = errors.New("test")
+	assert.Equal(t, http.StatusServiceUnavailable, get(HealthPath))
+
+	b.state.store(phaseStopping)
+	assert.Equal(t, http.StatusServiceUnavailable, get(ReadyPath))
+}
+
+func Test_healthRunner_RunStop(t *testing.T) {
+	hr := NewHealthRunner("127.0.0.1:0", New())
+	done := make(chan error)
+	go func() {
+		done <- hr.Run(context.Background())
+	}()
+	time.Sleep(time.Millisecond * 10)
+	assert.Nil(t, hr.Stop(context.Background()))
+	assert.Nil(t, <-done)
+
+	hr = NewHealthRunner("bad address", New())
+	assert.NotNil(t, hr.Run(context.Background()))
+}
+
+func TestBootstrap_Run_readinessFlag(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	opt, h := WithReadinessFlag()
+	status := func() int {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, ReadyPath, nil))
+		return rec.Code
+	}
+	r := NewMockRunner(ctrl)
+	r.EXPECT().Name().Return("testRunner").AnyTimes()
+	r.EXPECT().Run(gomock.Any()).DoAndReturn(func(ctx context.Context) error {
+		<-ctx.Done()
+		return nil
+	})
+	r.EXPECT().Stop(gomock.Any()).DoAndReturn(func(ctx context.Context) error {
+		assert.Equal(t, http.StatusServiceUnavailable, status())
+		return nil
+	})
+	b := New(WithRunners(r), opt)
+	assert.Equal(t, http.StatusServiceUnavailable, status())
+	done := make(chan error)
+	go func() {
+		done <- b.Run(ctx)
+	}()
+	assert.Eventually(t, func() bool {
+		return status() == http.StatusOK
+	}, time.Second, time.Millisecond)
+	cancel()
+	assert.Nil(t, <-done)
+	assert.Equal(t, http.StatusServiceUnavailable, status())
+}
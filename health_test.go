@@ -0,0 +1,40 @@
+package bootstrap
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+type healthCheckRunner struct {
+	*MockRunner
+	err error
+}
+
+func (r healthCheckRunner) HealthCheck(_ context.Context) error {
+	return r.err
+}
+
+func TestBootstrap_Health(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	plain := NewMockRunner(ctrl)
+	healthy := healthCheckRunner{MockRunner: NewMockRunner(ctrl)}
+	unhealthyErr := errors.New("test")
+	unhealthy := healthCheckRunner{MockRunner: NewMockRunner(ctrl), err: unhealthyErr}
+	unhealthy.EXPECT().Name().Return("unhealthyRunner").AnyTimes()
+
+	t.Run("healthy", func(t *testing.T) {
+		b := New(WithRunners(plain, healthy))
+		assert.Nil(t, b.Health(context.Background()))
+	})
+	t.Run("unhealthy", func(t *testing.T) {
+		b := New(WithRunners(plain, healthy, unhealthy))
+		err := b.Health(context.Background())
+		assert.ErrorIs(t, err, unhealthyErr)
+		assert.Contains(t, err.Error(), "unhealthyRunner")
+	})
+}
@@ -0,0 +1,87 @@
+package bootstrap
+
+import (
+	"encoding/json"
+	"os"
+	"sync"
+	"time"
+)
+
+// maxJournalSize bounds the bytes written to a journal during one boot.
+// Entries beyond the bound are dropped.
+const maxJournalSize = 1 << 20
+
+const (
+	journalBoot          = "boot"
+	journalRunnerStart   = "runner.start"
+	journalReady         = "ready"
+	journalRunnerStop    = "runner.stop"
+	journalRunnerStopped = "runner.stopped"
+	journalExit          = "exit"
+)
+
+// JournalEntry is a lifecycle transition recorded in the journal file configured by WithJournal.
+type JournalEntry struct {
+	Time   time.Time `json:"time"`
+	Event  string    `json:"event"`
+	Runner string    `json:"runner,omitempty"`
+	Error  string    `json:"error,omitempty"`
+}
+
+// journal appends JournalEntry lines to a file. A nil journal discards all entries.
+type journal struct {
+	mux     sync.Mutex
+	f       *os.File
+	written int
+	clock   Clock
+}
+
+// openJournal opens the journal at path, truncating entries of previous boots.
+func openJournal(path string) (*journal, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	return &journal{f: f}, nil
+}
+
+// now returns the time of the journal clock, the real one by default.
+func (j *journal) now() time.Time {
+	if j.clock == nil {
+		return time.Now()
+	}
+	return j.clock.Now()
+}
+
+// record appends an entry. Critical entries are flushed to disk before returning.
+func (j *journal) record(event, runnerName string, err error, critical bool) {
+	if j == nil {
+		return
+	}
+	entry := JournalEntry{Time: j.now(), Event: event, Runner: runnerName}
+	if err != nil {
+		entry.Error = err.Error()
+	}
+	line, mErr := json.Marshal(entry)
+	if mErr != nil {
+		return
+	}
+	line = append(line, '\n')
+	j.mux.Lock()
+	defer j.mux.Unlock()
+	if j.written+len(line) > maxJournalSize {
+		return
+	}
+	n, _ := j.f.Write(line)
+	j.written += n
+	if critical {
+		_ = j.f.Sync()
+	}
+}
+
+func (j *journal) close() error {
+	if j == nil {
+		return nil
+	}
+	return j.f.Close()
+}
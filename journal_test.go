@@ -0,0 +1,109 @@
+package bootstrap
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+func readJournal(t *testing.T, path string) []JournalEntry {
+	f, err := os.Open(path)
+	if !assert.Nil(t, err) {
+		return nil
+	}
+	defer func() { _ = f.Close() }()
+	var entries []JournalEntry
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		entry := JournalEntry{}
+		if assert.Nil(t, json.Unmarshal(scanner.Bytes(), &entry)) {
+			entries = append(entries, entry)
+		}
+	}
+	return entries
+}
+
+func journalEvents(entries []JournalEntry) []string {
+	events := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		events = append(events, entry.Event)
+	}
+	return events
+}
+
+func Test_journal(t *testing.T) {
+	t.Run("nil", func(t *testing.T) {
+		var j *journal
+		j.record(journalBoot, "", nil, true)
+		assert.Nil(t, j.close())
+	})
+	t.Run("truncate_on_boot", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "boot.journal")
+		j, err := openJournal(path)
+		assert.Nil(t, err)
+		j.record(journalBoot, "", nil, true)
+		j.record(journalExit, "", nil, true)
+		assert.Nil(t, j.close())
+		j, err = openJournal(path)
+		assert.Nil(t, err)
+		j.record(journalBoot, "", nil, true)
+		assert.Nil(t, j.close())
+		assert.Equal(t, []string{journalBoot}, journalEvents(readJournal(t, path)))
+	})
+	t.Run("bounded", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "boot.journal")
+		j, err := openJournal(path)
+		assert.Nil(t, err)
+		j.written = maxJournalSize
+		j.record(journalBoot, "", nil, true)
+		assert.Nil(t, j.close())
+		assert.Empty(t, readJournal(t, path))
+	})
+	t.Run("open_fail", func(t *testing.T) {
+		_, err := openJournal(filepath.Join(t.TempDir(), "absent", "boot.journal"))
+		assert.NotNil(t, err)
+	})
+}
+
+func TestBootstrap_Run_journal(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	path := filepath.Join(t.TempDir(), "boot.journal")
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	r := NewMockRunner(ctrl)
+	r.EXPECT().Name().Return("testRunner").AnyTimes()
+	running := make(chan struct{})
+	r.EXPECT().Run(gomock.Any()).DoAndReturn(func(ctx context.Context) error {
+		close(running)
+		<-ctx.Done()
+		return nil
+	})
+	r.EXPECT().Stop(gomock.Any()).Return(nil)
+	b := New(WithRunners(r), WithJournal(path))
+	done := make(chan error)
+	go func() {
+		done <- b.Run(ctx)
+	}()
+	<-running
+	// The process may die at any time from now on, what is journaled so far must be readable.
+	assert.Eventually(t, func() bool {
+		events := journalEvents(readJournal(t, path))
+		return len(events) > 0 && events[len(events)-1] == journalReady
+	}, time.Second, time.Millisecond)
+	crashed := readJournal(t, path)
+	assert.Equal(t, []string{journalBoot, journalRunnerStart, journalReady}, journalEvents(crashed))
+	assert.Equal(t, "testRunner", crashed[1].Runner)
+	cancel()
+	assert.Nil(t, <-done)
+	assert.Equal(t, []string{
+		journalBoot, journalRunnerStart, journalReady, journalRunnerStop, journalRunnerStopped, journalExit,
+	}, journalEvents(readJournal(t, path)))
+}
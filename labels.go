@@ -0,0 +1,78 @@
+package bootstrap
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"golang.org/x/exp/slog"
+)
+
+// LabeledMetrics is a Metrics which also observes the labels of the runners, see WithRunnerLabels.
+// The labels are nil for runners without labels.
+type LabeledMetrics interface {
+	Metrics
+	// ObserveStartLabeled observes the duration a runner took to start.
+	ObserveStartLabeled(name string, labels map[string]string, d time.Duration)
+	// ObserveStopLabeled observes the duration a runner took to stop, and the error stopping it if any.
+	ObserveStopLabeled(name string, labels map[string]string, d time.Duration, err error)
+}
+
+// labelArgs returns the labels of the runner named name as log attrs, ordered by key.
+func (b bootstrap) labelArgs(name string) []any {
+	labels := b.runnerLabels[name]
+	if len(labels) == 0 {
+		return nil
+	}
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	args := make([]any, 0, len(keys))
+	for _, k := range keys {
+		args = append(args, slog.String(k, labels[k]))
+	}
+	return args
+}
+
+func (b bootstrap) observeStart(name string, d time.Duration) {
+	if m, ok := b.metrics.(LabeledMetrics); ok {
+		m.ObserveStartLabeled(name, b.runnerLabels[name], d)
+		return
+	}
+	b.metrics.ObserveStart(name, d)
+}
+
+func (b bootstrap) observeStop(name string, d time.Duration, err error) {
+	if m, ok := b.metrics.(LabeledMetrics); ok {
+		m.ObserveStopLabeled(name, b.runnerLabels[name], d, err)
+		return
+	}
+	b.metrics.ObserveStop(name, d, err)
+}
+
+// checkLabels warns about the labels configured for runners not in entries.
+func (l *lifecycle) checkLabels(entries []*runnerEntry) {
+	names := make([]string, 0, len(l.b.runnerLabels))
+	for name := range l.b.runnerLabels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		found := false
+		for _, e := range entries {
+			if e.r.Name() == name {
+				found = true
+				break
+			}
+		}
+		if found {
+			continue
+		}
+		if l.logger.Enabled(slog.WarnLevel) {
+			l.logger.Warn(fmt.Sprintf("Labels configured for runner %s, which is not found", name))
+		}
+		l.b.warn(name, "labels configured for an unknown runner", nil)
+	}
+}
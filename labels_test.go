@@ -0,0 +1,70 @@
+package bootstrap
+
+import (
+	"bytes"
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/exp/slog"
+)
+
+type labeledMetrics struct {
+	fakeMetrics
+	mux    sync.Mutex
+	labels map[string]map[string]string
+}
+
+func (m *labeledMetrics) ObserveStartLabeled(name string, labels map[string]string, _ time.Duration) {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	if m.labels == nil {
+		m.labels = map[string]map[string]string{}
+	}
+	m.labels[name] = labels
+}
+
+func (m *labeledMetrics) ObserveStopLabeled(string, map[string]string, time.Duration, error) {}
+
+func TestBootstrap_Run_runnerLabels(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	logBuf := &bytes.Buffer{}
+	ctx, cancel := context.WithCancel(bufLogCtx(context.Background(), logBuf))
+	defer cancel()
+	r := NewMockRunner(ctrl)
+	r.EXPECT().Name().Return("testRunner").AnyTimes()
+	r.EXPECT().Run(gomock.Any()).DoAndReturn(func(ctx context.Context) error {
+		<-ctx.Done()
+		return nil
+	})
+	r.EXPECT().Stop(gomock.Any()).Return(nil)
+	m := &labeledMetrics{}
+	labels := map[string]string{"env": "prod", "region": "eu"}
+	b := New(WithRunners(r), WithMetrics(m),
+		WithRunnerLabels("testRunner", labels), WithRunnerLabels("unknown", labels))
+	go func() {
+		<-time.After(time.Millisecond * 10)
+		cancel()
+	}()
+	assert.Nil(t, b.Run(ctx))
+	var starting, unknown map[string]any
+	for _, m := range printAndJson(t, logBuf) {
+		switch m[slog.MessageKey] {
+		case "Starting runner: testRunner":
+			starting = m
+		case "Labels configured for runner unknown, which is not found":
+			unknown = m
+		}
+	}
+	if assert.NotNil(t, starting) {
+		assert.Equal(t, "prod", starting["env"])
+		assert.Equal(t, "eu", starting["region"])
+	}
+	assert.NotNil(t, unknown)
+	assert.Contains(t, b.Warnings(), Warning{Runner: "unknown", Message: "labels configured for an unknown runner"})
+	assert.Equal(t, map[string]map[string]string{"testRunner": labels}, m.labels)
+}
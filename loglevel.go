@@ -0,0 +1,66 @@
+package bootstrap
+
+import (
+	"fmt"
+	"os"
+
+	"golang.org/x/exp/slog"
+)
+
+// WithDebugToggleSignal makes sig toggle the debug logs of the bootstrap, e.g. SIGUSR2 to diagnose
+// a stuck shutdown without redeploying. The level of its lifecycle logs is then Info, or Debug once
+// toggled, within the level of the handler: it must enable Debug for the toggle to show them.
+// The logs of the runners are left to the handler.
+func WithDebugToggleSignal(sig os.Signal) Option {
+	return func(b *bootstrap) {
+		b.debugSignal = sig
+	}
+}
+
+// levelHandler is a handler whose level is raised by a LevelVar, above the level of the handler.
+type levelHandler struct {
+	slog.Handler
+	level *slog.LevelVar
+}
+
+func (h levelHandler) Enabled(level slog.Level) bool {
+	return level >= h.level.Level() && h.Handler.Enabled(level)
+}
+
+func (h levelHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return levelHandler{Handler: h.Handler.WithAttrs(attrs), level: h.level}
+}
+
+func (h levelHandler) WithGroup(name string) slog.Handler {
+	return levelHandler{Handler: h.Handler.WithGroup(name), level: h.level}
+}
+
+// withLevelVar returns logger with its level set by a LevelVar, Info by default, and the LevelVar.
+func withLevelVar(logger *slog.Logger) (*slog.Logger, *slog.LevelVar) {
+	level := &slog.LevelVar{}
+	level.Set(slog.InfoLevel)
+	return slog.New(levelHandler{Handler: logger.Handler(), level: level}), level
+}
+
+// watchDebugToggle toggles level between Info and Debug each time the debug toggle signal is received,
+// until the lifecycle is done.
+func (l *lifecycle) watchDebugToggle(level *slog.LevelVar) {
+	ch := make(chan os.Signal, 1)
+	signalNotify(ch, l.b.debugSignal)
+	go func() {
+		defer signalStop(ch)
+		for {
+			select {
+			case <-ch:
+			case <-l.done:
+				return
+			}
+			if level.Level() == slog.DebugLevel {
+				level.Set(slog.InfoLevel)
+			} else {
+				level.Set(slog.DebugLevel)
+			}
+			l.logger.Log(slog.InfoLevel, fmt.Sprintf("Received %s, log level set to %s", l.b.debugSignal, level.Level()))
+		}
+	}()
+}
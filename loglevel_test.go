@@ -0,0 +1,109 @@
+package bootstrap
+
+import (
+	"bytes"
+	"context"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/exp/slog"
+)
+
+func TestWithDebugToggleSignal(t *testing.T) {
+	b := bootstrap{}
+	WithDebugToggleSignal(syscall.SIGUSR2)(&b)
+	assert.Equal(t, syscall.SIGUSR2, b.debugSignal)
+}
+
+func Test_withLevelVar(t *testing.T) {
+	buf := &bytes.Buffer{}
+	handler := slog.HandlerOptions{Level: slog.DebugLevel}.NewJSONHandler(buf)
+	logger, level := withLevelVar(slog.New(handler))
+	logger = logger.With(slog.String("k", "v")).WithGroup("g")
+	logger.Debug("hidden")
+	level.Set(slog.DebugLevel)
+	logger.Debug("shown")
+	assert.NotContains(t, buf.String(), "hidden")
+	assert.Contains(t, buf.String(), "shown")
+	// The level of the handler still applies.
+	buf.Reset()
+	logger, level = withLevelVar(slog.New(slog.HandlerOptions{Level: slog.WarnLevel}.NewJSONHandler(buf)))
+	level.Set(slog.DebugLevel)
+	logger.Debug("debug")
+	logger.Info("info")
+	logger.Warn("warn")
+	assert.NotContains(t, buf.String(), "debug")
+	assert.NotContains(t, buf.String(), "info")
+	assert.Contains(t, buf.String(), "warn")
+}
+
+func TestBootstrap_Run_debugToggle(t *testing.T) {
+	// run runs a bootstrap toggling its debug logs toggles times before shutting it down,
+	// and returns its logs.
+	run := func(t *testing.T, toggles int) string {
+		fs := installFakeSignals(t)
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		r := NewMockRunner(ctrl)
+		r.EXPECT().Name().Return("testRunner").AnyTimes()
+		r.EXPECT().Run(gomock.Any()).DoAndReturn(func(ctx context.Context) error {
+			slog.Ctx(ctx).Debug("runner debug")
+			<-ctx.Done()
+			return nil
+		})
+		r.EXPECT().Stop(gomock.Any()).Return(nil)
+		buf := &bytes.Buffer{}
+		w := &lockedWriter{w: buf}
+		logs := func() string {
+			w.mux.Lock()
+			defer w.mux.Unlock()
+			return buf.String()
+		}
+		b := New(WithRunners(r), WithDebugToggleSignal(syscall.SIGUSR2))
+		done := make(chan error)
+		go func() {
+			handler := slog.HandlerOptions{Level: slog.DebugLevel}.NewJSONHandler(w)
+			done <- b.Run(slog.NewContext(ctx, slog.New(handler)))
+		}()
+		assert.Eventually(t, b.Ready, time.Second, time.Millisecond)
+		for i := 1; i <= toggles; i++ {
+			assert.Equal(t, 1, fs.send(syscall.SIGUSR2))
+			want := bytes.Count([]byte(logs()), []byte("log level set to")) + 1
+			assert.Eventually(t, func() bool {
+				return bytes.Count([]byte(logs()), []byte("log level set to")) == want
+			}, time.Second, time.Millisecond)
+		}
+		cancel()
+		assert.Nil(t, <-done)
+		// The toggle stops watching the signal once the run is done.
+		assert.Eventually(t, func() bool {
+			fs.mux.Lock()
+			defer fs.mux.Unlock()
+			return len(fs.subs) == 0
+		}, time.Second, time.Millisecond)
+		return logs()
+	}
+	t.Run("off", func(t *testing.T) {
+		logs := run(t, 0)
+		assert.Contains(t, logs, "Stopping runner: testRunner")
+		assert.NotContains(t, logs, "awaits its turn to stop")
+		// The logs of the runners are left to the handler.
+		assert.Contains(t, logs, "runner debug")
+	})
+	t.Run("on", func(t *testing.T) {
+		logs := run(t, 1)
+		assert.Contains(t, logs, "Received user defined signal 2, log level set to DEBUG")
+		assert.Contains(t, logs, "Runner testRunner awaits its turn to stop")
+		assert.Contains(t, logs, "Stop of runner testRunner returned after ")
+	})
+	t.Run("toggled_back", func(t *testing.T) {
+		logs := run(t, 2)
+		assert.Contains(t, logs, "log level set to INFO")
+		assert.NotContains(t, logs, "awaits its turn to stop")
+	})
+}
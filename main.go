@@ -0,0 +1,49 @@
+package bootstrap
+
+import (
+	"context"
+)
+
+// MainOption configures Main.
+type MainOption func(c *mainConfig)
+
+type mainConfig struct {
+	ctx      context.Context
+	exitCode func(err error) int
+}
+
+// WithMainContext sets the context Main runs the Bootstrap with. Defaults to context.Background().
+func WithMainContext(ctx context.Context) MainOption {
+	return func(c *mainConfig) {
+		c.ctx = ctx
+	}
+}
+
+// WithExitCodeFunc sets the func mapping the error returned by Run to the process exit code.
+// It is not called if Run returns nil, which always exits with 0.
+// Defaults to exiting with 1.
+func WithExitCodeFunc(fn func(err error) int) MainOption {
+	return func(c *mainConfig) {
+		c.exitCode = fn
+	}
+}
+
+// Main runs b and exits the process with the exit code mapped from the result.
+// It is meant to be the last call of a main function.
+func Main(b Bootstrap, opts ...MainOption) {
+	c := &mainConfig{
+		ctx: context.Background(),
+		exitCode: func(err error) int {
+			return 1
+		},
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	err := b.Run(c.ctx)
+	if err == nil {
+		exitFunc(0)
+		return
+	}
+	exitFunc(c.exitCode(err))
+}
@@ -0,0 +1,57 @@
+package bootstrap
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMain_exitCode(t *testing.T) {
+	exit := exitFunc
+	defer func() {
+		exitFunc = exit
+	}()
+	var code int
+	exitFunc = func(c int) {
+		code = c
+	}
+	runErr := errors.New("test")
+	newBootstrap := func(ctrl *gomock.Controller, err error) Bootstrap {
+		r := NewMockRunner(ctrl)
+		r.EXPECT().Name().Return("testRunner").AnyTimes()
+		r.EXPECT().Run(gomock.Any()).Return(err)
+		r.EXPECT().Stop(gomock.Any()).Return(nil).AnyTimes()
+		return New(WithRunners(r))
+	}
+	t.Run("success", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		code = -1
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		Main(newBootstrap(ctrl, nil), WithMainContext(ctx))
+		assert.Equal(t, 0, code)
+	})
+	t.Run("default", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		code = -1
+		Main(newBootstrap(ctrl, runErr))
+		assert.Equal(t, 1, code)
+	})
+	t.Run("func", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		code = -1
+		Main(newBootstrap(ctrl, runErr), WithExitCodeFunc(func(err error) int {
+			if errors.Is(err, runErr) {
+				return 3
+			}
+			return 1
+		}))
+		assert.Equal(t, 3, code)
+	})
+}
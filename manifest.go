@@ -0,0 +1,70 @@
+package bootstrap
+
+import (
+	"encoding/json"
+	"io"
+	"strconv"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+
+	"github.com/yimi-go/runner"
+)
+
+// manifestEntry is a runner entry of a manifest.
+type manifestEntry struct {
+	Type   string    `yaml:"type"`
+	Name   string    `yaml:"name"`
+	Config yaml.Node `yaml:"config"`
+}
+
+// LoadRunners reads a manifest from r, a JSON or YAML list of {"type", "name", "config"} entries,
+// and returns the options adding the runners it describes, in order.
+// Each runner is built by the constructor registered for its type, with its config as JSON.
+// If the entry has a name, the runner built must be named so.
+func LoadRunners(r io.Reader, registry map[string]func(json.RawMessage) (runner.Runner, error)) ([]Option, error) {
+	// JSON is YAML too.
+	var entries []manifestEntry
+	if err := yaml.NewDecoder(r).Decode(&entries); err != nil {
+		return nil, errors.WithMessage(err, "decode runner manifest failed")
+	}
+	runners := make([]runner.Runner, 0, len(entries))
+	for i, entry := range entries {
+		name := entry.Name
+		if name == "" {
+			name = "#" + strconv.Itoa(i)
+		}
+		construct, ok := registry[entry.Type]
+		if !ok {
+			return nil, errors.Errorf("runner %s: unknown type %q", name, entry.Type)
+		}
+		config, err := manifestConfig(&entry.Config)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "runner %s: decode config failed", name)
+		}
+		rr, err := construct(config)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "runner %s: construct %s failed", name, entry.Type)
+		}
+		if rr == nil {
+			return nil, errors.Errorf("runner %s: construct %s returned no runner", name, entry.Type)
+		}
+		if entry.Name != "" && rr.Name() != entry.Name {
+			return nil, errors.Errorf("runner %s: construct %s returned a runner named %q", name, entry.Type, rr.Name())
+		}
+		runners = append(runners, rr)
+	}
+	return []Option{WithRunners(runners...)}, nil
+}
+
+// manifestConfig returns the config node of a manifest entry as JSON, or nil if there is none.
+func manifestConfig(node *yaml.Node) (json.RawMessage, error) {
+	if node.Kind == 0 {
+		return nil, nil
+	}
+	var v any
+	if err := node.Decode(&v); err != nil {
+		return nil, err
+	}
+	return json.Marshal(v)
+}
@@ -0,0 +1,81 @@
+package bootstrap
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/yimi-go/runner"
+)
+
+func TestLoadRunners(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	var configs []string
+	registry := map[string]func(json.RawMessage) (runner.Runner, error){
+		"http": func(config json.RawMessage) (runner.Runner, error) {
+			var c struct {
+				Addr string `json:"addr"`
+			}
+			if err := json.Unmarshal(config, &c); err != nil {
+				return nil, err
+			}
+			configs = append(configs, c.Addr)
+			r := NewMockRunner(ctrl)
+			r.EXPECT().Name().Return("http" + c.Addr).AnyTimes()
+			return r, nil
+		},
+		"fail": func(config json.RawMessage) (runner.Runner, error) {
+			return nil, errors.New("test")
+		},
+	}
+	t.Run("ok", func(t *testing.T) {
+		configs = nil
+		opts, err := LoadRunners(strings.NewReader(`[
+			{"type": "http", "name": "http:80", "config": {"addr": ":80"}},
+			{"type": "http", "config": {"addr": ":8080"}}
+		]`), registry)
+		assert.Nil(t, err)
+		assert.Equal(t, []string{":80", ":8080"}, configs)
+		b := New(opts...).(bootstrap)
+		if assert.Len(t, b.runners, 2) {
+			assert.Equal(t, "http:80", b.runners[0].Name())
+			assert.Equal(t, "http:8080", b.runners[1].Name())
+		}
+	})
+	t.Run("yaml", func(t *testing.T) {
+		configs = nil
+		opts, err := LoadRunners(strings.NewReader(`
+- type: http
+  name: http:80
+  config:
+    addr: ":80"
+`), registry)
+		assert.Nil(t, err)
+		assert.Equal(t, []string{":80"}, configs)
+		b := New(opts...).(bootstrap)
+		if assert.Len(t, b.runners, 1) {
+			assert.Equal(t, "http:80", b.runners[0].Name())
+		}
+	})
+	t.Run("name_mismatch", func(t *testing.T) {
+		_, err := LoadRunners(strings.NewReader(`[{"type": "http", "name": "public", "config": {"addr": ":80"}}]`), registry)
+		assert.EqualError(t, err, `runner public: construct http returned a runner named "http:80"`)
+	})
+	t.Run("bad_json", func(t *testing.T) {
+		_, err := LoadRunners(strings.NewReader(`{`), registry)
+		assert.ErrorContains(t, err, "decode runner manifest failed")
+	})
+	t.Run("unknown_type", func(t *testing.T) {
+		_, err := LoadRunners(strings.NewReader(`[{"type": "grpc", "name": "api"}]`), registry)
+		assert.EqualError(t, err, `runner api: unknown type "grpc"`)
+	})
+	t.Run("construct_error", func(t *testing.T) {
+		_, err := LoadRunners(strings.NewReader(`[{"type": "fail"}]`), registry)
+		assert.EqualError(t, err, "runner #0: construct fail failed: test")
+	})
+}
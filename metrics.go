@@ -0,0 +1,19 @@
+package bootstrap
+
+import (
+	"time"
+)
+
+// Metrics observes the lifecycle durations of runners.
+type Metrics interface {
+	// ObserveStart observes the duration a runner took to start, until ready if it is a Readier.
+	ObserveStart(name string, d time.Duration)
+	// ObserveStop observes the duration a runner took to stop, and the error stopping it if any.
+	ObserveStop(name string, d time.Duration, err error)
+}
+
+type noopMetrics struct{}
+
+func (noopMetrics) ObserveStart(string, time.Duration) {}
+
+func (noopMetrics) ObserveStop(string, time.Duration, error) {}
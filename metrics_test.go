@@ -0,0 +1,98 @@
+package bootstrap
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeMetrics struct {
+	mux       sync.Mutex
+	starts    []string
+	durations map[string]time.Duration
+	stops     map[string]error
+}
+
+func (m *fakeMetrics) ObserveStart(name string, d time.Duration) {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	m.starts = append(m.starts, name)
+	if m.durations == nil {
+		m.durations = map[string]time.Duration{}
+	}
+	m.durations[name] = d
+}
+
+func (m *fakeMetrics) ObserveStop(name string, _ time.Duration, err error) {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	if m.stops == nil {
+		m.stops = map[string]error{}
+	}
+	m.stops[name] = err
+}
+
+func Test_noopMetrics(t *testing.T) {
+	m := noopMetrics{}
+	m.ObserveStart("testRunner", time.Second)
+	m.ObserveStop("testRunner", time.Second, nil)
+}
+
+func TestBootstrap_Run_metrics(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	r := NewMockRunner(ctrl)
+	r.EXPECT().Name().Return("testRunner").AnyTimes()
+	r.EXPECT().Run(gomock.Any()).DoAndReturn(func(ctx context.Context) error {
+		<-ctx.Done()
+		return nil
+	})
+	stopErr := errors.New("test")
+	r.EXPECT().Stop(gomock.Any()).Return(stopErr)
+	m := &fakeMetrics{}
+	b := New(WithRunners(r), WithMetrics(m))
+	go func() {
+		<-time.After(time.Millisecond * 10)
+		cancel()
+	}()
+	assert.Nil(t, b.Run(ctx))
+	assert.Equal(t, []string{"testRunner"}, m.starts)
+	assert.Equal(t, map[string]error{"testRunner": stopErr}, m.stops)
+}
+
+func TestBootstrap_Run_metrics_readier(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	r := readierRunner{MockRunner: NewMockRunner(ctrl), waitReady: func(ctx context.Context) error {
+		time.Sleep(time.Millisecond * 50)
+		return nil
+	}}
+	m := &fakeMetrics{}
+	r.EXPECT().Name().Return("testRunner").AnyTimes()
+	r.EXPECT().Run(gomock.Any()).DoAndReturn(func(ctx context.Context) error {
+		m.mux.Lock()
+		// Not started until ready.
+		assert.Empty(t, m.starts)
+		m.mux.Unlock()
+		<-ctx.Done()
+		return nil
+	})
+	r.EXPECT().Stop(gomock.Any()).Return(nil)
+	b := New(WithRunners(r), WithMetrics(m), WithOnRun(func(ctx context.Context) error {
+		cancel()
+		return nil
+	}))
+	assert.Nil(t, b.Run(ctx))
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	assert.Equal(t, []string{"testRunner"}, m.starts)
+}
@@ -0,0 +1,28 @@
+package bootstrap
+
+// OnRunLifecycle defines how onRun is treated when shutdown begins.
+type OnRunLifecycle int
+
+const (
+	// OnRunCancel cancels the context of onRun as soon as shutdown begins. It is the default.
+	OnRunCancel OnRunLifecycle = iota
+	// OnRunDrain lets onRun keep running while runners are being stopped.
+	// Its context is cancelled once all runners have been stopped.
+	OnRunDrain
+	// OnRunComplete makes shutdown wait for onRun to return before stopping any runner.
+	// The wait is bounded by the shutdown context.
+	OnRunComplete
+)
+
+func (l OnRunLifecycle) String() string {
+	switch l {
+	case OnRunCancel:
+		return "cancel"
+	case OnRunDrain:
+		return "drain"
+	case OnRunComplete:
+		return "complete"
+	default:
+		return "unknown"
+	}
+}
@@ -0,0 +1,244 @@
+package bootstrap
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/yimi-go/shutdown"
+)
+
+func TestOnRunLifecycle_String(t *testing.T) {
+	assert.Equal(t, "cancel", OnRunCancel.String())
+	assert.Equal(t, "drain", OnRunDrain.String())
+	assert.Equal(t, "complete", OnRunComplete.String())
+	assert.Equal(t, "unknown", OnRunLifecycle(-1).String())
+}
+
+func TestBootstrap_Run_onRunLifecycle(t *testing.T) {
+	// run runs a bootstrap with onRun in mode l, shutting it down without cancelling the run context.
+	// It returns whether the onRun context was done, and whether onRun had returned, when the runner stopped.
+	run := func(t *testing.T, l OnRunLifecycle, onRun func(ctx context.Context) error) (ctxDone, returned bool) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		gs := shutdown.NewGraceful(shutdown.WithTimeout(time.Second))
+		onRunCtx := make(chan context.Context, 1)
+		onRunReturned := &atomic.Bool{}
+		stop := make(chan struct{})
+		r := NewMockRunner(ctrl)
+		r.EXPECT().Name().Return("testRunner").AnyTimes()
+		r.EXPECT().Run(gomock.Any()).DoAndReturn(func(ctx context.Context) error {
+			<-stop
+			return nil
+		})
+		r.EXPECT().Stop(gomock.Any()).DoAndReturn(func(ctx context.Context) error {
+			ctxDone = (<-onRunCtx).Err() != nil
+			returned = onRunReturned.Load()
+			close(stop)
+			return nil
+		})
+		b := New(WithRunners(r), WithShutdown(gs), WithOnRunLifecycle(l), WithOnRun(func(ctx context.Context) error {
+			onRunCtx <- ctx
+			defer onRunReturned.Store(true)
+			return onRun(ctx)
+		}))
+		done := make(chan error)
+		go func() {
+			done <- b.Run(context.Background())
+		}()
+		assert.Eventually(t, b.Ready, time.Second, time.Millisecond)
+		gs.HandleShutdown(context.Background(), shutdown.EventFunc(func() string {
+			return "test"
+		}))
+		select {
+		case err := <-done:
+			assert.Nil(t, err)
+		case <-time.After(time.Second):
+			t.Error("timeout")
+		}
+		return
+	}
+	waitCtx := func(ctx context.Context) error {
+		<-ctx.Done()
+		return nil
+	}
+	t.Run("cancel", func(t *testing.T) {
+		ctxDone, _ := run(t, OnRunCancel, waitCtx)
+		assert.True(t, ctxDone)
+	})
+	t.Run("drain", func(t *testing.T) {
+		ctxDone, returned := run(t, OnRunDrain, waitCtx)
+		assert.False(t, ctxDone)
+		assert.False(t, returned)
+	})
+	t.Run("complete", func(t *testing.T) {
+		_, returned := run(t, OnRunComplete, func(ctx context.Context) error {
+			time.Sleep(time.Millisecond * 50)
+			return nil
+		})
+		assert.True(t, returned)
+	})
+}
+
+func TestBootstrap_Run_onRunTriggersShutdown(t *testing.T) {
+	for _, l := range []OnRunLifecycle{OnRunCancel, OnRunDrain, OnRunComplete} {
+		t.Run(l.String(), func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+			stop := make(chan struct{})
+			r := NewMockRunner(ctrl)
+			r.EXPECT().Name().Return("testRunner").AnyTimes()
+			r.EXPECT().Run(gomock.Any()).DoAndReturn(func(ctx context.Context) error {
+				<-stop
+				return nil
+			})
+			r.EXPECT().Stop(gomock.Any()).DoAndReturn(func(ctx context.Context) error {
+				close(stop)
+				return nil
+			})
+			b := New(WithRunners(r), WithOnRunLifecycle(l), WithOnRunTriggersShutdown(true),
+				WithOnRun(func(ctx context.Context) error {
+					return nil
+				}))
+			done := make(chan error)
+			go func() {
+				done <- b.Run(context.Background())
+			}()
+			select {
+			case err := <-done:
+				assert.Nil(t, err)
+			case <-time.After(time.Second):
+				t.Fatal("timeout")
+			}
+			var shutdownErr *ShutdownError
+			if assert.ErrorAs(t, b.StopCause(), &shutdownErr) {
+				assert.Equal(t, CauseOnRunCompleted, shutdownErr.Cause)
+			}
+		})
+	}
+}
+
+func TestBootstrap_Run_onRunDone(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	stop := make(chan struct{})
+	r := NewMockRunner(ctrl)
+	r.EXPECT().Name().Return("testRunner").AnyTimes()
+	r.EXPECT().Run(gomock.Any()).DoAndReturn(func(ctx context.Context) error {
+		<-stop
+		return nil
+	})
+	r.EXPECT().Stop(gomock.Any()).DoAndReturn(func(ctx context.Context) error {
+		close(stop)
+		return nil
+	})
+	b := New(WithRunners(r), WithOnRun(func(ctx context.Context) error {
+		return fmt.Errorf("work finished: %w", ErrDone)
+	}))
+	done := make(chan error)
+	go func() {
+		done <- b.Run(context.Background())
+	}()
+	select {
+	case err := <-done:
+		assert.Nil(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("timeout")
+	}
+	var shutdownErr *ShutdownError
+	if assert.ErrorAs(t, b.StopCause(), &shutdownErr) {
+		assert.Equal(t, CauseOnRunCompleted, shutdownErr.Cause)
+	}
+}
+
+func TestBootstrap_Run_startupBarrier(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	r := NewMockRunner(ctrl)
+	r.EXPECT().Name().Return("testRunner").AnyTimes()
+	r.EXPECT().Run(gomock.Any()).DoAndReturn(func(ctx context.Context) error {
+		<-ctx.Done()
+		return nil
+	})
+	r.EXPECT().Stop(gomock.Any()).Return(nil)
+	var b Bootstrap
+	ready := false
+	b = New(WithRunners(r), WithReadyDelay(time.Millisecond*50), WithStartupBarrier(true),
+		WithOnRun(func(context.Context) error {
+			ready = b.Ready()
+			cancel()
+			return nil
+		}))
+	assert.Nil(t, b.Run(ctx))
+	assert.True(t, ready)
+}
+
+func TestBootstrap_Run_job(t *testing.T) {
+	t.Run("completed", func(t *testing.T) {
+		var ran atomic.Bool
+		b := New(WithOnRun(func(ctx context.Context) error {
+			ran.Store(true)
+			return nil
+		}))
+		done := make(chan error)
+		go func() {
+			done <- b.Run(context.Background())
+		}()
+		select {
+		case err := <-done:
+			assert.Nil(t, err)
+		case <-time.After(time.Second):
+			t.Fatal("timeout")
+		}
+		assert.True(t, ran.Load())
+		var shutdownErr *ShutdownError
+		if assert.ErrorAs(t, b.StopCause(), &shutdownErr) {
+			assert.Equal(t, CauseOnRunCompleted, shutdownErr.Cause)
+		}
+	})
+	t.Run("canceled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		started := make(chan struct{})
+		b := New(WithOnRun(func(ctx context.Context) error {
+			close(started)
+			<-ctx.Done()
+			return nil
+		}))
+		done := make(chan error)
+		go func() {
+			done <- b.Run(ctx)
+		}()
+		<-started
+		cancel()
+		select {
+		case err := <-done:
+			assert.Nil(t, err)
+		case <-time.After(time.Second):
+			t.Fatal("timeout")
+		}
+	})
+	t.Run("failed", func(t *testing.T) {
+		onRunErr := errors.New("test")
+		b := New(WithOnRun(func(ctx context.Context) error {
+			return onRunErr
+		}))
+		done := make(chan error)
+		go func() {
+			done <- b.Run(context.Background())
+		}()
+		select {
+		case err := <-done:
+			assert.ErrorIs(t, err, onRunErr)
+		case <-time.After(time.Second):
+			t.Fatal("timeout")
+		}
+	})
+}
@@ -2,6 +2,12 @@ package bootstrap
 
 import (
 	"context"
+	"net/http"
+	"os"
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
+	"golang.org/x/exp/slog"
 
 	"github.com/yimi-go/runner"
 	"github.com/yimi-go/shutdown"
@@ -15,6 +21,19 @@ func WithShutdown(gs shutdown.Controller) Option {
 			return
 		}
 		b.gs = gs
+		// The triggers of a custom controller are managed by its creator, see WithControllerTriggers.
+		b.triggers = nil
+		b.customShutdown = true
+	}
+}
+
+// WithControllerTriggers declares the triggers the shutdown controller set by WithShutdown was
+// built with, none if ts is empty. Without it, the triggers of such a controller are unknown, and
+// Run does not check whether it can be stopped, see WithStrictShutdownTriggers.
+func WithControllerTriggers(ts ...shutdown.Trigger) Option {
+	return func(b *bootstrap) {
+		b.controllerTriggers = append(b.controllerTriggers, ts...)
+		b.controllerTriggersDeclared = true
 	}
 }
 
@@ -24,14 +43,581 @@ func WithBeforeRun(before func(ctx context.Context) error) Option {
 	}
 }
 
+// WithOnRun sets fn to run once the bootstrap started. fn may return ErrDone to shut it down gracefully.
+// Without runners, fn runs as a job: the bootstrap shuts down once it returns.
 func WithOnRun(fn func(ctx context.Context) error) Option {
 	return func(b *bootstrap) {
 		b.onRun = fn
 	}
 }
 
+// WithRunners adds runners. Nil runners are dropped, Run logs a warning about them.
 func WithRunners(rs ...runner.Runner) Option {
 	return func(b *bootstrap) {
+		b.runners = append(b.runners, b.dropNil(rs)...)
+	}
+}
+
+func WithStopTimeout(d time.Duration) Option {
+	return func(b *bootstrap) {
+		b.stopTimeout = d
+	}
+}
+
+func WithReturnStopErrors(enable bool) Option {
+	return func(b *bootstrap) {
+		b.returnStopErrors = enable
+	}
+}
+
+func WithStartupAllocReport() Option {
+	return func(b *bootstrap) {
+		b.allocReport = true
+	}
+}
+
+func WithStartupAllocLimit(bytes uint64) Option {
+	return func(b *bootstrap) {
+		b.allocLimit = bytes
+	}
+}
+
+func WithJournal(path string) Option {
+	return func(b *bootstrap) {
+		b.journalPath = path
+	}
+}
+
+func WithReadyDelay(d time.Duration) Option {
+	return func(b *bootstrap) {
+		b.readyDelay = d
+	}
+}
+
+// WithStrictShutdownTriggers makes Run fail with ErrUnstoppable instead of warning
+// if its context is not cancellable and no shutdown trigger is configured.
+// The triggers of a controller set by WithShutdown must be declared for the check to apply,
+// see WithControllerTriggers.
+func WithStrictShutdownTriggers() Option {
+	return func(b *bootstrap) {
+		b.strictTriggers = true
+	}
+}
+
+// WithDeterministicStartLogging makes the "Starting runner" logs emitted sorted by runner name
+// instead of in goroutine scheduling order. Runners still start concurrently.
+func WithDeterministicStartLogging() Option {
+	return func(b *bootstrap) {
+		b.sortedStartLogs = true
+	}
+}
+
+// WithTracerProvider traces Run, the runners' starts, runs and stops with spans from tp:
+// "bootstrap.run", and "runner.start/<name>" until the runner is ready, "runner.run/<name>" until it
+// returns and "runner.stop/<name>". Tracing is disabled if tp is nil.
+func WithTracerProvider(tp trace.TracerProvider) Option {
+	return func(b *bootstrap) {
+		if tp == nil {
+			b.tracer = nil
+			return
+		}
+		b.tracer = tp.Tracer(tracerName)
+	}
+}
+
+// WithReadinessFlag returns an Option and an http.Handler reporting the readiness of the bootstrap
+// built with the Option. The readiness flips to false as soon as shutdown begins,
+// before any runner is stopped, so load balancers polling the handler stop routing first.
+func WithReadinessFlag() (Option, http.Handler) {
+	h := &readinessHandler{}
+	return func(b *bootstrap) {
+		h.b = b
+	}, h
+}
+
+func WithMetrics(m Metrics) Option {
+	return func(b *bootstrap) {
+		if m == nil {
+			return
+		}
+		b.metrics = m
+	}
+}
+
+// WithMinUptime delays stopping runners until the bootstrap has been running for at least d,
+// or the shutdown context is done, smoothing out shutdowns requested right after booting.
+func WithMinUptime(d time.Duration) Option {
+	return func(b *bootstrap) {
+		b.minUptime = d
+	}
+}
+
+func WithOnRunLifecycle(l OnRunLifecycle) Option {
+	return func(b *bootstrap) {
+		b.onRunLifecycle = l
+	}
+}
+
+// WithForceExitOnSecondSignal makes the process exit immediately with code,
+// if SIGINT or SIGTERM is received while shutting down.
+func WithForceExitOnSecondSignal(code int) Option {
+	return func(b *bootstrap) {
+		b.forceExit = true
+		b.forceExitCode = code
+	}
+}
+
+// WithOptionalRunners adds best-effort runners. An optional runner failing is logged
+// but does not stop the other runners. It is still stopped on shutdown.
+func WithOptionalRunners(rs ...runner.Runner) Option {
+	return func(b *bootstrap) {
+		if b.optional == nil {
+			b.optional = map[string]struct{}{}
+		}
+		rs = b.dropNil(rs)
+		for _, r := range rs {
+			b.optional[r.Name()] = struct{}{}
+		}
+		b.runners = append(b.runners, rs...)
+	}
+}
+
+// WithDynamicRunnerWindow sets when AddRunner accepts runners. Defaults to UntilRun.
+// Runners added after a Run has returned are started by the next Run.
+func WithDynamicRunnerWindow(w RunnerWindow) Option {
+	return func(b *bootstrap) {
+		b.runnerWindow = w
+	}
+}
+
+// WithMaxConcurrentStart limits how many runners are in their startup phase at once.
+// A runner leaves its startup phase once ready, see Readier. n <= 0 means no limit.
+func WithMaxConcurrentStart(n int) Option {
+	return func(b *bootstrap) {
+		b.maxConcurrentStart = n
+	}
+}
+
+// WithDependency declares that the runner named dependent depends on the runner named dependsOn.
+// The dependent is started once dependsOn is ready, see Readier, and stopped before it.
+// Run returns an error wrapping ErrDependencyCycle if the dependencies form a cycle.
+func WithDependency(dependent, dependsOn string) Option {
+	return func(b *bootstrap) {
+		if b.dependencies == nil {
+			b.dependencies = map[string][]string{}
+		}
+		b.dependencies[dependent] = append(b.dependencies[dependent], dependsOn)
+	}
+}
+
+// WithCauseTimeout sets the shutdown timeout used when the shutdown is triggered by cause,
+// instead of the timeout of the shutdown controller.
+func WithCauseTimeout(cause ShutdownCause, d time.Duration) Option {
+	return func(b *bootstrap) {
+		if b.causeTimeouts == nil {
+			b.causeTimeouts = map[ShutdownCause]time.Duration{}
+		}
+		b.causeTimeouts[cause] = d
+	}
+}
+
+// WithOnRunnerStart sets the func called with each runner just before it runs.
+func WithOnRunnerStart(fn func(ctx context.Context, r runner.Runner)) Option {
+	return func(b *bootstrap) {
+		b.onRunnerStart = fn
+	}
+}
+
+// WithOnRunnerStop sets the func called with each runner once it is stopped, with the stop error.
+func WithOnRunnerStop(fn func(ctx context.Context, r runner.Runner, err error)) Option {
+	return func(b *bootstrap) {
+		b.onRunnerStop = fn
+	}
+}
+
+// WithRunnerGroup adds runners in the shutdown phase phase. On shutdown, the phases are stopped
+// one after another in ascending order, the runners of a phase concurrently.
+// Runners not added by WithRunnerGroup are in phase 0. The phases must agree with the dependencies
+// and priorities, Run returns an error wrapping ErrOrderConflict otherwise.
+func WithRunnerGroup(phase int, rs ...runner.Runner) Option {
+	return func(b *bootstrap) {
+		if b.phases == nil {
+			b.phases = map[string]int{}
+		}
+		rs = b.dropNil(rs)
+		for _, r := range rs {
+			b.phases[r.Name()] = phase
+		}
 		b.runners = append(b.runners, rs...)
 	}
 }
+
+// WithAfterReady sets a func run once all runners have started, after "bootstrap started."
+// is logged. If it fails, the bootstrap is shut down gracefully and Run returns the error.
+func WithAfterReady(fn func(ctx context.Context) error) Option {
+	return func(b *bootstrap) {
+		b.afterReady = fn
+	}
+}
+
+// WithShutdownErrorHandler sets the handler of the shutdown errors, e.g. of failing stops,
+// instead of logging them. It applies to the default shutdown controller only,
+// a controller set by WithShutdown keeps its own handler.
+func WithShutdownErrorHandler(fn func(ctx context.Context, err error)) Option {
+	return func(b *bootstrap) {
+		if fn == nil {
+			return
+		}
+		b.shutdownErrorHandler = fn
+	}
+}
+
+// WithTriggers adds shutdown triggers, e.g. an admin command. They are added to the triggers of
+// the shutdown controller, the POSIX signal trigger of the default one included.
+func WithTriggers(ts ...shutdown.Trigger) Option {
+	return func(b *bootstrap) {
+		b.extraTriggers = append(b.extraTriggers, ts...)
+	}
+}
+
+// WithDrainDelay sets a pause between the beginning of the shutdown, which turns the readiness
+// false, and the stop of the runners, so that load balancers stop routing traffic meanwhile.
+func WithDrainDelay(d time.Duration) Option {
+	return func(b *bootstrap) {
+		b.drainDelay = d
+	}
+}
+
+// WithInFlightGrace delays the cancellation of the contexts of the runners by d, once the run context
+// is done, e.g. by a shutdown signal or a runner failure, so that they finish their in-flight work.
+// The stop callbacks are called meanwhile. The grace is not bounded by the shutdown timeout:
+// runners returning only once their context is done delay Run by up to d, keep it shorter.
+// Nor does it extend the deadline of the run context, which the contexts of the runners keep.
+func WithInFlightGrace(d time.Duration) Option {
+	return func(b *bootstrap) {
+		b.inFlightGrace = d
+	}
+}
+
+// WithSlowStartupThreshold flags the startups, from Run to "bootstrap started.", taking longer
+// than d: a warning is logged, and cb, if not nil, is called with the startup duration.
+func WithSlowStartupThreshold(d time.Duration, cb func(d time.Duration)) Option {
+	return func(b *bootstrap) {
+		b.slowStartup = d
+		b.onSlowStartup = cb
+	}
+}
+
+// WithName sets the name of the bootstrap, attached to its logs as the "bootstrap" attribute.
+func WithName(name string) Option {
+	return func(b *bootstrap) {
+		b.name = name
+	}
+}
+
+// WithRunnerFactory adds a factory producing runners at Run time, after beforeRun succeeded,
+// e.g. runners needing resources created by beforeRun. The runners are added to the run set.
+// A factory error aborts Run.
+func WithRunnerFactory(factory func(ctx context.Context) ([]runner.Runner, error)) Option {
+	return func(b *bootstrap) {
+		if factory == nil {
+			return
+		}
+		b.factories = append(b.factories, factory)
+	}
+}
+
+// WithBeforeRunTimeout bounds beforeRun with a timeout. Run returns a *PhaseError if it is exceeded.
+func WithBeforeRunTimeout(d time.Duration) Option {
+	return func(b *bootstrap) {
+		b.beforeRunTimeout = d
+	}
+}
+
+// WithBeforeRunRetry retries a failing beforeRun up to attempts times in total, waiting backoff
+// between the attempts, or until the Run context is done. Each attempt is bounded by the before
+// run timeout, see WithBeforeRunTimeout.
+func WithBeforeRunRetry(attempts int, backoff time.Duration) Option {
+	return func(b *bootstrap) {
+		b.beforeRunAttempts = attempts
+		b.beforeRunBackoff = backoff
+	}
+}
+
+// WithWaitFor adds checks of the external systems the runners depend on, e.g. a database.
+// They run after beforeRun and before the runners start, and are retried with backoff until they
+// succeed. Run fails if one of them does not succeed within the timeout, see WithWaitForTimeout.
+func WithWaitFor(checks ...func(ctx context.Context) error) Option {
+	return func(b *bootstrap) {
+		for _, check := range checks {
+			if check != nil {
+				b.waitForChecks = append(b.waitForChecks, check)
+			}
+		}
+	}
+}
+
+// WithWaitForTimeout bounds the wait for the checks added by WithWaitFor, 30s by default.
+func WithWaitForTimeout(d time.Duration) Option {
+	return func(b *bootstrap) {
+		b.waitForTimeout = d
+	}
+}
+
+// WithStopOrder stops the runners named names one after the other, in order, before the runners
+// not listed, which then stop one after the other in registration order. It replaces the stop
+// order of the priorities, see Prioritizer. Dependencies still come first: a runner stops before
+// its dependencies, and Run returns an error wrapping ErrStopOrderConflict if names contradict it.
+// It is mutually exclusive with WithShutdownOrderFunc, the last one set wins, with a warning.
+func WithStopOrder(names ...string) Option {
+	return func(b *bootstrap) {
+		if b.stopLess != nil {
+			b.stopLess = nil
+			b.stopOrderOverridden = true
+		}
+		b.stopOrder = append(b.stopOrder, names...)
+	}
+}
+
+// WithShutdownOrderFunc stops the runners one after the other, sorted by less, stably: runners
+// less does not order stop in registration order. It replaces the stop order of the priorities,
+// see Prioritizer. Dependencies still come first: a runner stops before its dependencies, whatever
+// less tells. It is mutually exclusive with WithStopOrder, the last one set wins, with a warning.
+func WithShutdownOrderFunc(less func(a, b runner.Runner) bool) Option {
+	return func(b *bootstrap) {
+		if less == nil {
+			return
+		}
+		if len(b.stopOrder) > 0 {
+			b.stopOrder = nil
+			b.stopOrderOverridden = true
+		}
+		b.stopLess = less
+	}
+}
+
+// WithStartupProbe sets probe to report the startup progress: it is called each time a runner
+// gets ready during the startup, with the number of runners ready and the total number of runners.
+// The calls are sequential, the last one reports total runners ready once they all are.
+func WithStartupProbe(probe func(started, total int)) Option {
+	return func(b *bootstrap) {
+		b.startupProbe = probe
+	}
+}
+
+// WithSupervised restarts the runner named name when it fails, or panics if cfg recovers panics,
+// until it exceeds the restarts of cfg: its last failure then shuts the bootstrap down, as for
+// unsupervised runners. Runners are not restarted once the shutdown began.
+func WithSupervised(name string, cfg SupervisorConfig) Option {
+	return func(b *bootstrap) {
+		if b.supervised == nil {
+			b.supervised = map[string]SupervisorConfig{}
+		}
+		b.supervised[name] = cfg
+	}
+}
+
+// WithStartRetry retries the runner named name while its run fails with errors isRetryable reports,
+// e.g. as a dependency is not ready yet, up to attempts times in total, waiting backoff between them.
+// Other errors fail it right away, as for runners without retry. Runners are not retried once the
+// shutdown began.
+func WithStartRetry(name string, isRetryable func(err error) bool, attempts int, backoff time.Duration) Option {
+	return func(b *bootstrap) {
+		if isRetryable == nil {
+			return
+		}
+		if b.startRetries == nil {
+			b.startRetries = map[string]startRetry{}
+		}
+		b.startRetries[name] = startRetry{isRetryable: isRetryable, attempts: attempts, backoff: backoff}
+	}
+}
+
+// WithShutdownProbe sets probe to report the shutdown progress: it is called each time a runner
+// stop callback completes, with the number of runners stopped and the total number of runners.
+// The calls are sequential, the last one reports total runners stopped once they all are.
+func WithShutdownProbe(probe func(stopped, total int)) Option {
+	return func(b *bootstrap) {
+		b.shutdownProbe = probe
+	}
+}
+
+// WithStartHook adds hook to set the runners of each Run: called after beforeRun and the runner
+// factories, it returns the runners to run from the current ones, e.g. filtered or extended.
+// Hooks are called in order. Run fails if hook fails, or returns nil runners or duplicate names.
+func WithStartHook(hook func(ctx context.Context, current []runner.Runner) ([]runner.Runner, error)) Option {
+	return func(b *bootstrap) {
+		if hook == nil {
+			return
+		}
+		b.startHooks = append(b.startHooks, hook)
+	}
+}
+
+// WithStartupDeadline bounds the wait for the runners to signal their start.
+// Run returns a *StartupTimeoutError listing the pending runners if it is exceeded.
+func WithStartupDeadline(d time.Duration) Option {
+	return func(b *bootstrap) {
+		b.startupDeadline = d
+	}
+}
+
+// WithRunnerTimeout bounds the run of the runner named name: its context is cancelled after d,
+// and its run fails with ErrRunnerTimeout. Like any runner failure, it shuts the bootstrap down,
+// unless the runner is optional, see WithOptionalRunners.
+func WithRunnerTimeout(name string, d time.Duration) Option {
+	return func(b *bootstrap) {
+		if b.runnerTimeouts == nil {
+			b.runnerTimeouts = map[string]time.Duration{}
+		}
+		b.runnerTimeouts[name] = d
+	}
+}
+
+// WithRunnerLabels attaches labels to the runner named name, e.g. its environment or region.
+// They are added to its lifecycle logs and its logger, and observed by a LabeledMetrics.
+// Labels of the same runner are merged, the last value of a key wins.
+func WithRunnerLabels(name string, labels map[string]string) Option {
+	return func(b *bootstrap) {
+		if b.runnerLabels == nil {
+			b.runnerLabels = map[string]map[string]string{}
+		}
+		merged := b.runnerLabels[name]
+		if merged == nil {
+			merged = map[string]string{}
+			b.runnerLabels[name] = merged
+		}
+		for k, v := range labels {
+			merged[k] = v
+		}
+	}
+}
+
+// WithRunnerContext derives the context of the runner named name with fn, e.g. to give it values
+// the other runners must not see, like a scoped credential. fn is called with the context the
+// runner would get otherwise. The last fn set for a runner wins.
+func WithRunnerContext(name string, fn func(parent context.Context) context.Context) Option {
+	return func(b *bootstrap) {
+		if b.runnerContexts == nil {
+			b.runnerContexts = map[string]func(parent context.Context) context.Context{}
+		}
+		b.runnerContexts[name] = fn
+	}
+}
+
+// WithOnRunTriggersShutdown makes onRun returning nil trigger the graceful shutdown of the runners,
+// e.g. for a one-shot job driven by onRun. Run then returns once they are stopped.
+func WithOnRunTriggersShutdown(enable bool) Option {
+	return func(b *bootstrap) {
+		b.onRunTriggersShutdown = enable
+	}
+}
+
+// WithStartupBarrier makes onRun wait for the bootstrap to be ready, see Ready, before running.
+// onRun does not run if shutdown begins first.
+func WithStartupBarrier(enable bool) Option {
+	return func(b *bootstrap) {
+		b.startupBarrier = enable
+	}
+}
+
+// WithGroupConcurrency limits the runners running at once to n, queuing the others until a running
+// one returns. The internal goroutines of the run group are not counted, so the startup completes
+// for n >= the runner count. A runner blocking until it is stopped holds its slot until then: with
+// fewer slots than such runners, the queued ones and thus the startup wait for the shutdown.
+// Use WithMaxConcurrentStart to only bound the startups. n <= 0 means no limit.
+func WithGroupConcurrency(n int) Option {
+	return func(b *bootstrap) {
+		b.groupConcurrency = n
+	}
+}
+
+// WithShutdownContext makes the runners stop under a fresh context returned by fn, instead of one
+// derived from the context passed to the shutdown controller, which may be cancelled already.
+// The shutdown timeout still applies: the timeout of the controller, unless overridden, see
+// WithCauseTimeout and Bootstrap.SetShutdownTimeout.
+func WithShutdownContext(fn func() context.Context) Option {
+	return func(b *bootstrap) {
+		b.shutdownCtx = fn
+	}
+}
+
+// WithLoggerAttrsFunc sets a func computing attributes at the start of each Run, e.g. a run ID,
+// attached to all the logs of the Run.
+func WithLoggerAttrsFunc(fn func(ctx context.Context) []slog.Attr) Option {
+	return func(b *bootstrap) {
+		b.loggerAttrsFunc = fn
+	}
+}
+
+// Timeouts are the timeouts of the phases of a bootstrap. Zero means no timeout.
+type Timeouts struct {
+	// BeforeRun bounds beforeRun, see WithBeforeRunTimeout.
+	BeforeRun time.Duration
+	// Startup bounds the startup of the runners, see WithStartupDeadline.
+	Startup time.Duration
+	// Shutdown bounds the shutdown. It is the timeout of the default shutdown controller,
+	// 1s if zero, and overrides the one of a custom controller, see Bootstrap.SetShutdownTimeout.
+	Shutdown time.Duration
+	// PerRunnerStop bounds the stop of each runner, see WithStopTimeout.
+	PerRunnerStop time.Duration
+}
+
+// WithTimeouts sets all the phase timeouts at once, replacing the ones set before.
+func WithTimeouts(t Timeouts) Option {
+	return func(b *bootstrap) {
+		b.beforeRunTimeout = t.BeforeRun
+		b.startupDeadline = t.Startup
+		b.shutdownTimeout = t.Shutdown
+		b.stopTimeout = t.PerRunnerStop
+	}
+}
+
+// WithOnSignal sets a func called with the signal a shutdown is triggered by, before the shutdown.
+// The signal triggers are observed, i.e. the default POSIX signal trigger, and the triggers added
+// with WithTriggers, but not the ones of a custom shutdown controller.
+func WithOnSignal(fn func(sig os.Signal)) Option {
+	return func(b *bootstrap) {
+		b.onSignal = fn
+	}
+}
+
+// WithReload sets a func reloading e.g. the configuration, run each time SIGHUP is received once
+// the bootstrap started. Its failures are logged, SIGHUP does not trigger shutdown.
+func WithReload(fn func(ctx context.Context) error) Option {
+	return func(b *bootstrap) {
+		b.reload = fn
+	}
+}
+
+// WithStartErrorPolicy sets how the runners failing to start are handled, FailFast by default.
+func WithStartErrorPolicy(policy StartErrorPolicy) Option {
+	return func(b *bootstrap) {
+		b.startErrorPolicy = policy
+	}
+}
+
+// WithRunTimeout bounds the whole Run: the bootstrap is shut down once d elapsed since Run was called,
+// as if its context were done, and Run returns an error wrapping ErrRunTimeout.
+func WithRunTimeout(d time.Duration) Option {
+	return func(b *bootstrap) {
+		b.runTimeout = d
+	}
+}
+
+// WithStopConcurrency limits how many runners are stopped at once: 1 stops them one at a time,
+// n <= 0 means no limit, the default. The shutdown ordering, e.g. dependencies, still applies.
+func WithStopConcurrency(n int) Option {
+	return func(b *bootstrap) {
+		b.stopConcurrency = n
+	}
+}
+
+// WithLogSampler sets a func telling whether to emit the lifecycle log event of a runner, one of
+// LogEventStart, LogEventStop and LogEventStopped, e.g. to keep the logs of hundreds of runners short.
+// Errors and warnings are always emitted.
+func WithLogSampler(sampler func(runnerName string, event string) bool) Option {
+	return func(b *bootstrap) {
+		b.logSampler = sampler
+	}
+}
@@ -2,10 +2,18 @@ package bootstrap
 
 import (
 	"context"
+	"os"
 	"testing"
+	"time"
 
 	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/trace"
+	"golang.org/x/exp/slog"
+
+	"github.com/yimi-go/runner"
+	"github.com/yimi-go/shutdown"
+	"github.com/yimi-go/shutdown/posixsignal"
 )
 
 func TestWithShutdown(t *testing.T) {
@@ -15,10 +23,21 @@ func TestWithShutdown(t *testing.T) {
 	b := bootstrap{}
 	WithShutdown(c)(&b)
 	assert.Same(t, c, b.gs)
+	assert.Empty(t, b.triggers)
+	assert.True(t, b.customShutdown)
 	WithShutdown(nil)(&b)
 	assert.Same(t, c, b.gs)
 }
 
+func TestWithControllerTriggers(t *testing.T) {
+	b := bootstrap{}
+	WithControllerTriggers()(&b)
+	assert.True(t, b.controllerTriggersDeclared)
+	assert.Empty(t, b.controllerTriggers)
+	WithControllerTriggers(posixsignal.NewTrigger())(&b)
+	assert.Len(t, b.controllerTriggers, 1)
+}
+
 func TestWithBeforeRun(t *testing.T) {
 	count := 0
 	b := bootstrap{}
@@ -51,4 +70,449 @@ func TestWithRunners(t *testing.T) {
 	b := bootstrap{}
 	WithRunners(NewMockRunner(ctrl), NewMockRunner(ctrl))(&b)
 	assert.Len(t, b.runners, 2)
+	var nilRunner *MockRunner
+	WithRunners(nil, nilRunner)(&b)
+	assert.Len(t, b.runners, 2)
+	assert.Equal(t, 2, b.nilRunners)
+}
+
+func TestWithStopTimeout(t *testing.T) {
+	b := bootstrap{}
+	WithStopTimeout(time.Second)(&b)
+	assert.Equal(t, time.Second, b.stopTimeout)
+}
+
+func TestWithReturnStopErrors(t *testing.T) {
+	b := bootstrap{}
+	WithReturnStopErrors(true)(&b)
+	assert.True(t, b.returnStopErrors)
+}
+
+func TestWithStartupAllocReport(t *testing.T) {
+	b := bootstrap{}
+	WithStartupAllocReport()(&b)
+	assert.True(t, b.allocReport)
+}
+
+func TestWithStartupAllocLimit(t *testing.T) {
+	b := bootstrap{}
+	WithStartupAllocLimit(1024)(&b)
+	assert.Equal(t, uint64(1024), b.allocLimit)
+}
+
+func TestWithJournal(t *testing.T) {
+	b := bootstrap{}
+	WithJournal("boot.journal")(&b)
+	assert.Equal(t, "boot.journal", b.journalPath)
+}
+
+func TestWithReadyDelay(t *testing.T) {
+	b := bootstrap{}
+	WithReadyDelay(time.Second)(&b)
+	assert.Equal(t, time.Second, b.readyDelay)
+}
+
+func TestWithStrictShutdownTriggers(t *testing.T) {
+	b := bootstrap{}
+	WithStrictShutdownTriggers()(&b)
+	assert.True(t, b.strictTriggers)
+}
+
+func TestWithDeterministicStartLogging(t *testing.T) {
+	b := bootstrap{}
+	WithDeterministicStartLogging()(&b)
+	assert.True(t, b.sortedStartLogs)
+}
+
+func TestWithTracerProvider(t *testing.T) {
+	b := bootstrap{}
+	WithTracerProvider(trace.NewNoopTracerProvider())(&b)
+	assert.NotNil(t, b.tracer)
+	WithTracerProvider(nil)(&b)
+	assert.Nil(t, b.tracer)
+}
+
+func TestWithReadinessFlag(t *testing.T) {
+	b := bootstrap{}
+	opt, h := WithReadinessFlag()
+	assert.Nil(t, h.(*readinessHandler).b)
+	opt(&b)
+	assert.Same(t, &b, h.(*readinessHandler).b)
+}
+
+func TestWithMetrics(t *testing.T) {
+	b := bootstrap{metrics: noopMetrics{}}
+	WithMetrics(nil)(&b)
+	assert.Equal(t, noopMetrics{}, b.metrics)
+	m := &fakeMetrics{}
+	WithMetrics(m)(&b)
+	assert.Same(t, m, b.metrics)
+}
+
+func TestWithMinUptime(t *testing.T) {
+	b := bootstrap{}
+	WithMinUptime(time.Second)(&b)
+	assert.Equal(t, time.Second, b.minUptime)
+}
+
+func TestWithOnRunLifecycle(t *testing.T) {
+	b := bootstrap{}
+	WithOnRunLifecycle(OnRunDrain)(&b)
+	assert.Equal(t, OnRunDrain, b.onRunLifecycle)
+}
+
+func TestWithForceExitOnSecondSignal(t *testing.T) {
+	b := bootstrap{}
+	WithForceExitOnSecondSignal(130)(&b)
+	assert.True(t, b.forceExit)
+	assert.Equal(t, 130, b.forceExitCode)
+}
+
+func TestWithOptionalRunners(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	r1, r2 := NewMockRunner(ctrl), NewMockRunner(ctrl)
+	r1.EXPECT().Name().Return("r1").AnyTimes()
+	r2.EXPECT().Name().Return("r2").AnyTimes()
+	// A runner of a type which is not comparable.
+	r3 := struct {
+		*MockRunner
+		tags []string
+	}{MockRunner: NewMockRunner(ctrl)}
+	r3.EXPECT().Name().Return("r3").AnyTimes()
+	b := bootstrap{}
+	WithRunners(r1)(&b)
+	WithOptionalRunners(r2, r3)(&b)
+	assert.Len(t, b.runners, 3)
+	assert.False(t, b.isOptional(r1))
+	assert.True(t, b.isOptional(r2))
+	assert.True(t, b.isOptional(r3))
+}
+
+func TestWithDynamicRunnerWindow(t *testing.T) {
+	b := bootstrap{}
+	WithDynamicRunnerWindow(Always)(&b)
+	assert.Equal(t, Always, b.runnerWindow)
+}
+
+func TestWithMaxConcurrentStart(t *testing.T) {
+	b := bootstrap{}
+	WithMaxConcurrentStart(2)(&b)
+	assert.Equal(t, 2, b.maxConcurrentStart)
+}
+
+func TestWithDependency(t *testing.T) {
+	b := bootstrap{}
+	WithDependency("b", "a")(&b)
+	WithDependency("b", "c")(&b)
+	assert.Equal(t, map[string][]string{"b": {"a", "c"}}, b.dependencies)
+}
+
+func TestWithStopOrder(t *testing.T) {
+	b := bootstrap{}
+	WithStopOrder("b", "a")(&b)
+	WithStopOrder("c")(&b)
+	assert.Equal(t, []string{"b", "a", "c"}, b.stopOrder)
+	assert.False(t, b.stopOrderOverridden)
+}
+
+func TestWithShutdownOrderFunc(t *testing.T) {
+	b := bootstrap{}
+	WithShutdownOrderFunc(nil)(&b)
+	assert.Nil(t, b.stopLess)
+	WithStopOrder("a")(&b)
+	WithShutdownOrderFunc(func(a, b runner.Runner) bool {
+		return a.Name() < b.Name()
+	})(&b)
+	assert.NotNil(t, b.stopLess)
+	assert.Empty(t, b.stopOrder)
+	assert.True(t, b.stopOrderOverridden)
+	WithStopOrder("a")(&b)
+	assert.Nil(t, b.stopLess)
+	assert.Equal(t, []string{"a"}, b.stopOrder)
+}
+
+func TestWithCauseTimeout(t *testing.T) {
+	b := bootstrap{}
+	WithCauseTimeout(CauseSignal, time.Second)(&b)
+	assert.Equal(t, map[ShutdownCause]time.Duration{CauseSignal: time.Second}, b.causeTimeouts)
+}
+
+func TestWithOnRunnerStart(t *testing.T) {
+	b := bootstrap{}
+	WithOnRunnerStart(func(ctx context.Context, r runner.Runner) {})(&b)
+	assert.NotNil(t, b.onRunnerStart)
+}
+
+func TestWithOnRunnerStop(t *testing.T) {
+	b := bootstrap{}
+	WithOnRunnerStop(func(ctx context.Context, r runner.Runner, err error) {})(&b)
+	assert.NotNil(t, b.onRunnerStop)
+}
+
+func TestWithRunnerGroup(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	r := NewMockRunner(ctrl)
+	r.EXPECT().Name().Return("testRunner").AnyTimes()
+	b := bootstrap{}
+	WithRunnerGroup(2, r, nil)(&b)
+	assert.Equal(t, []runner.Runner{r}, b.runners)
+	assert.Equal(t, map[string]int{"testRunner": 2}, b.phases)
+}
+
+func TestWithAfterReady(t *testing.T) {
+	b := bootstrap{}
+	WithAfterReady(func(ctx context.Context) error {
+		return nil
+	})(&b)
+	assert.NotNil(t, b.afterReady)
+}
+
+func TestWithShutdownErrorHandler(t *testing.T) {
+	b := bootstrap{}
+	WithShutdownErrorHandler(nil)(&b)
+	assert.Nil(t, b.shutdownErrorHandler)
+	WithShutdownErrorHandler(func(ctx context.Context, err error) {})(&b)
+	assert.NotNil(t, b.shutdownErrorHandler)
+}
+
+func TestWithTriggers(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	tr := NewMockTrigger(ctrl)
+	b := bootstrap{}
+	WithTriggers(tr)(&b)
+	assert.Equal(t, []shutdown.Trigger{tr}, b.extraTriggers)
+}
+
+func TestWithInFlightGrace(t *testing.T) {
+	b := bootstrap{}
+	WithInFlightGrace(time.Second)(&b)
+	assert.Equal(t, time.Second, b.inFlightGrace)
+}
+
+func TestWithSlowStartupThreshold(t *testing.T) {
+	b := bootstrap{}
+	var got time.Duration
+	WithSlowStartupThreshold(time.Second, func(d time.Duration) {
+		got = d
+	})(&b)
+	assert.Equal(t, time.Second, b.slowStartup)
+	b.onSlowStartup(time.Minute)
+	assert.Equal(t, time.Minute, got)
+}
+
+func TestWithDrainDelay(t *testing.T) {
+	b := bootstrap{}
+	WithDrainDelay(time.Second)(&b)
+	assert.Equal(t, time.Second, b.drainDelay)
+}
+
+func TestWithName(t *testing.T) {
+	b := bootstrap{}
+	WithName("test")(&b)
+	assert.Equal(t, "test", b.name)
+}
+
+func TestWithRunnerFactory(t *testing.T) {
+	b := bootstrap{}
+	WithRunnerFactory(nil)(&b)
+	assert.Empty(t, b.factories)
+	WithRunnerFactory(func(ctx context.Context) ([]runner.Runner, error) {
+		return nil, nil
+	})(&b)
+	assert.Len(t, b.factories, 1)
+}
+
+func TestWithStartHook(t *testing.T) {
+	b := bootstrap{}
+	WithStartHook(nil)(&b)
+	assert.Empty(t, b.startHooks)
+	WithStartHook(func(ctx context.Context, current []runner.Runner) ([]runner.Runner, error) {
+		return current, nil
+	})(&b)
+	assert.Len(t, b.startHooks, 1)
+}
+
+func TestWithBeforeRunTimeout(t *testing.T) {
+	b := bootstrap{}
+	WithBeforeRunTimeout(time.Second)(&b)
+	assert.Equal(t, time.Second, b.beforeRunTimeout)
+}
+
+func TestWithStartupDeadline(t *testing.T) {
+	b := bootstrap{}
+	WithStartupDeadline(time.Second)(&b)
+	assert.Equal(t, time.Second, b.startupDeadline)
+}
+
+func TestWithRunnerTimeout(t *testing.T) {
+	b := bootstrap{}
+	WithRunnerTimeout("a", time.Second)(&b)
+	WithRunnerTimeout("b", time.Minute)(&b)
+	assert.Equal(t, map[string]time.Duration{"a": time.Second, "b": time.Minute}, b.runnerTimeouts)
+}
+
+func TestWithRunnerContext(t *testing.T) {
+	type key struct{}
+	b := bootstrap{}
+	WithRunnerContext("a", func(parent context.Context) context.Context {
+		return context.WithValue(parent, key{}, "a")
+	})(&b)
+	if assert.Contains(t, b.runnerContexts, "a") {
+		assert.Equal(t, "a", b.runnerContexts["a"](context.Background()).Value(key{}))
+	}
+}
+
+func TestWithBeforeRunRetry(t *testing.T) {
+	b := bootstrap{}
+	WithBeforeRunRetry(3, time.Second)(&b)
+	assert.Equal(t, 3, b.beforeRunAttempts)
+	assert.Equal(t, time.Second, b.beforeRunBackoff)
+}
+
+func TestWithWaitFor(t *testing.T) {
+	b := bootstrap{}
+	WithWaitFor(func(ctx context.Context) error { return nil }, nil)(&b)
+	WithWaitFor(func(ctx context.Context) error { return nil })(&b)
+	assert.Len(t, b.waitForChecks, 2)
+	WithWaitForTimeout(time.Second)(&b)
+	assert.Equal(t, time.Second, b.waitForTimeout)
+}
+
+func TestWithStartupProbe(t *testing.T) {
+	b := bootstrap{}
+	WithStartupProbe(func(started, total int) {})(&b)
+	assert.NotNil(t, b.startupProbe)
+}
+
+func TestWithSupervised(t *testing.T) {
+	b := bootstrap{}
+	cfg := SupervisorConfig{MaxRestarts: 3, Backoff: time.Second, RecoverPanics: true}
+	WithSupervised("a", cfg)(&b)
+	assert.Equal(t, map[string]SupervisorConfig{"a": cfg}, b.supervised)
+}
+
+func TestWithStartRetry(t *testing.T) {
+	b := bootstrap{}
+	WithStartRetry("a", nil, 3, time.Second)(&b)
+	assert.Empty(t, b.startRetries)
+	WithStartRetry("a", func(err error) bool {
+		return true
+	}, 3, time.Second)(&b)
+	if assert.Contains(t, b.startRetries, "a") {
+		assert.Equal(t, 3, b.startRetries["a"].attempts)
+		assert.Equal(t, time.Second, b.startRetries["a"].backoff)
+	}
+}
+
+func TestWithShutdownProbe(t *testing.T) {
+	b := bootstrap{}
+	WithShutdownProbe(func(stopped, total int) {})(&b)
+	assert.NotNil(t, b.shutdownProbe)
+}
+
+func TestWithRunnerLabels(t *testing.T) {
+	b := bootstrap{}
+	WithRunnerLabels("a", map[string]string{"env": "prod", "region": "eu"})(&b)
+	WithRunnerLabels("a", map[string]string{"region": "us"})(&b)
+	assert.Equal(t, map[string]map[string]string{"a": {"env": "prod", "region": "us"}}, b.runnerLabels)
+}
+
+func TestWithOnRunTriggersShutdown(t *testing.T) {
+	b := bootstrap{}
+	WithOnRunTriggersShutdown(true)(&b)
+	assert.True(t, b.onRunTriggersShutdown)
+}
+
+func TestWithStartupBarrier(t *testing.T) {
+	b := bootstrap{}
+	WithStartupBarrier(true)(&b)
+	assert.True(t, b.startupBarrier)
+}
+
+func TestWithGroupConcurrency(t *testing.T) {
+	b := bootstrap{}
+	WithGroupConcurrency(3)(&b)
+	assert.Equal(t, 3, b.groupConcurrency)
+}
+
+func TestWithShutdownContext(t *testing.T) {
+	b := bootstrap{}
+	ctx := context.Background()
+	WithShutdownContext(func() context.Context {
+		return ctx
+	})(&b)
+	assert.Equal(t, ctx, b.shutdownCtx())
+}
+
+func TestWithLoggerAttrsFunc(t *testing.T) {
+	b := bootstrap{}
+	WithLoggerAttrsFunc(func(ctx context.Context) []slog.Attr {
+		return []slog.Attr{slog.String("k", "v")}
+	})(&b)
+	assert.Equal(t, []slog.Attr{slog.String("k", "v")}, b.loggerAttrsFunc(context.Background()))
+}
+
+func TestWithTimeouts(t *testing.T) {
+	b := bootstrap{}
+	WithTimeouts(Timeouts{
+		BeforeRun:     time.Second,
+		Startup:       time.Second * 2,
+		Shutdown:      time.Second * 3,
+		PerRunnerStop: time.Second * 4,
+	})(&b)
+	assert.Equal(t, time.Second, b.beforeRunTimeout)
+	assert.Equal(t, time.Second*2, b.startupDeadline)
+	assert.Equal(t, time.Second*3, b.shutdownTimeout)
+	assert.Equal(t, time.Second*4, b.stopTimeout)
+	WithTimeouts(Timeouts{Startup: time.Second})(&b)
+	assert.Equal(t, bootstrap{startupDeadline: time.Second}, b)
+}
+
+func TestWithOnSignal(t *testing.T) {
+	b := bootstrap{}
+	var got os.Signal
+	WithOnSignal(func(sig os.Signal) {
+		got = sig
+	})(&b)
+	b.onSignal(os.Interrupt)
+	assert.Equal(t, os.Interrupt, got)
+}
+
+func TestWithReload(t *testing.T) {
+	b := bootstrap{}
+	WithReload(func(ctx context.Context) error {
+		return context.Canceled
+	})(&b)
+	assert.Equal(t, context.Canceled, b.reload(context.Background()))
+}
+
+func TestWithStartErrorPolicy(t *testing.T) {
+	b := bootstrap{}
+	WithStartErrorPolicy(BestEffort)(&b)
+	assert.Equal(t, BestEffort, b.startErrorPolicy)
+}
+
+func TestWithRunTimeout(t *testing.T) {
+	b := bootstrap{}
+	WithRunTimeout(time.Second)(&b)
+	assert.Equal(t, time.Second, b.runTimeout)
+}
+
+func TestWithStopConcurrency(t *testing.T) {
+	b := bootstrap{}
+	WithStopConcurrency(2)(&b)
+	assert.Equal(t, 2, b.stopConcurrency)
+}
+
+func TestWithLogSampler(t *testing.T) {
+	b := bootstrap{}
+	assert.True(t, b.sampled("a", LogEventStart))
+	WithLogSampler(func(runnerName string, event string) bool {
+		return event != LogEventStart
+	})(&b)
+	assert.False(t, b.sampled("a", LogEventStart))
+	assert.True(t, b.sampled("a", LogEventStop))
 }
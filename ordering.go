@@ -0,0 +1,110 @@
+package bootstrap
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/yimi-go/runner"
+)
+
+// stopAfter maps the names of the runners of rs to the names of the runners they stop after:
+// their dependents, the runners of lower shutdown phases, and the runners stopping before them by
+// the stop order, or else by priority. These are the constraints the stop callbacks wait for.
+func (b bootstrap) stopAfter(rs []runner.Runner) map[string][]string {
+	inSet := map[string]bool{}
+	for _, r := range rs {
+		inSet[r.Name()] = true
+	}
+	after := map[string][]string{}
+	add := func(name, before string) {
+		if name != before && inSet[before] && !containsString(after[name], before) {
+			after[name] = append(after[name], before)
+		}
+	}
+	var sequence []string
+	if b.hasStopOrder() {
+		sequence = b.stopSequence(rs)
+	}
+	for _, r := range rs {
+		name := r.Name()
+		for dependent, deps := range b.dependencies {
+			if containsString(deps, name) {
+				add(name, dependent)
+			}
+		}
+		for _, other := range rs {
+			if b.phases[other.Name()] < b.phases[name] {
+				add(name, other.Name())
+			}
+		}
+		if sequence != nil {
+			for _, other := range sequence {
+				if other == name {
+					break
+				}
+				add(name, other)
+			}
+			continue
+		}
+		for _, other := range rs {
+			if priorityOf(other) > priorityOf(r) && !b.dependsOn(name, other.Name()) {
+				add(name, other.Name())
+			}
+		}
+	}
+	return after
+}
+
+// checkOrdering returns an error wrapping ErrOrderConflict if the stop constraints of the runners
+// of rs form a cycle, which would hold the shutdown until it times out. See stopAfter.
+func (b bootstrap) checkOrdering(rs []runner.Runner) error {
+	if cycle := findCycle(b.stopAfter(rs)); cycle != nil {
+		return errors.WithMessage(ErrOrderConflict, strings.Join(cycle, " stops after "))
+	}
+	return nil
+}
+
+// findCycle returns a cycle of edges, starting and ending with the same node, or nil.
+func findCycle(edges map[string][]string) []string {
+	const (
+		visiting = iota + 1
+		visited
+	)
+	marks := map[string]int{}
+	var path []string
+	var visit func(name string) []string
+	visit = func(name string) []string {
+		switch marks[name] {
+		case visiting:
+			i := 0
+			for path[i] != name {
+				i++
+			}
+			return append(append([]string(nil), path[i:]...), name)
+		case visited:
+			return nil
+		}
+		marks[name] = visiting
+		path = append(path, name)
+		for _, next := range edges[name] {
+			if cycle := visit(next); cycle != nil {
+				return cycle
+			}
+		}
+		path = path[:len(path)-1]
+		marks[name] = visited
+		return nil
+	}
+	names := make([]string, 0, len(edges))
+	for name := range edges {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if cycle := visit(name); cycle != nil {
+			return cycle
+		}
+	}
+	return nil
+}
@@ -0,0 +1,52 @@
+package bootstrap
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBootstrap_checkOrdering(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	newRunner := func(name string) *MockRunner {
+		r := NewMockRunner(ctrl)
+		r.EXPECT().Name().Return(name).AnyTimes()
+		return r
+	}
+	assertConflict := func(t *testing.T, b Bootstrap) {
+		assert.ErrorIs(t, b.Validate(), ErrOrderConflict)
+		done := make(chan error)
+		go func() {
+			done <- b.Run(context.Background())
+		}()
+		select {
+		case err := <-done:
+			assert.ErrorIs(t, err, ErrOrderConflict)
+		case <-time.After(time.Second):
+			t.Fatal("Run not returned")
+		}
+	}
+	t.Run("consistent", func(t *testing.T) {
+		high := priorityRunner{MockRunner: newRunner("high"), priority: 1}
+		b := New(WithRunnerGroup(1, newRunner("dep")), WithRunners(newRunner("dependent"), high),
+			WithDependency("dependent", "dep"))
+		assert.Nil(t, b.(bootstrap).checkOrdering(b.(bootstrap).runnerSet()))
+	})
+	t.Run("phase_vs_priority", func(t *testing.T) {
+		// The phases stop low first, the priorities high first.
+		high := priorityRunner{MockRunner: newRunner("high"), priority: 1}
+		b := New(WithRunnerGroup(1, high), WithRunners(newRunner("low")))
+		assertConflict(t, b)
+	})
+	t.Run("phase_vs_dependency", func(t *testing.T) {
+		// The phases stop dep first, the dependency dependent first.
+		b := New(WithRunnerGroup(1, newRunner("dependent")), WithRunners(newRunner("dep")),
+			WithDependency("dependent", "dep"))
+		assertConflict(t, b)
+		assert.Contains(t, b.Validate().Error(), "dep stops after dependent stops after dep")
+	})
+}
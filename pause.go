@@ -0,0 +1,42 @@
+package bootstrap
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+)
+
+// Pausable is an optional interface a runner.Runner may implement to be paused without being stopped,
+// e.g. a job consumer which stops accepting new work during maintenance.
+type Pausable interface {
+	// Pause pauses the runner.
+	Pause(ctx context.Context) error
+	// Resume resumes the paused runner.
+	Resume(ctx context.Context) error
+}
+
+// Pause pauses all runners implementing Pausable and joins their errors.
+// Runners not implementing Pausable are skipped.
+func (b bootstrap) Pause(ctx context.Context) error {
+	return b.eachPausable(ctx, "pause", Pausable.Pause)
+}
+
+// Resume resumes all runners implementing Pausable and joins their errors.
+// Runners not implementing Pausable are skipped.
+func (b bootstrap) Resume(ctx context.Context) error {
+	return b.eachPausable(ctx, "resume", Pausable.Resume)
+}
+
+func (b bootstrap) eachPausable(ctx context.Context, action string, fn func(Pausable, context.Context) error) error {
+	var errs []error
+	for _, r := range b.activeRunners() {
+		p, ok := r.(Pausable)
+		if !ok {
+			continue
+		}
+		if err := fn(p, ctx); err != nil {
+			errs = append(errs, errors.WithMessagef(err, "%s runner %s failed", action, r.Name()))
+		}
+	}
+	return joinErrors(errs...)
+}
@@ -0,0 +1,53 @@
+package bootstrap
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+type pausableRunner struct {
+	*MockRunner
+	calls *[]string
+	err   error
+}
+
+func (r pausableRunner) Pause(_ context.Context) error {
+	*r.calls = append(*r.calls, "pause")
+	return r.err
+}
+
+func (r pausableRunner) Resume(_ context.Context) error {
+	*r.calls = append(*r.calls, "resume")
+	return r.err
+}
+
+func TestBootstrap_Pause(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	plain := NewMockRunner(ctrl)
+	var calls []string
+	pausable := pausableRunner{MockRunner: NewMockRunner(ctrl), calls: &calls}
+	t.Run("propagate", func(t *testing.T) {
+		b := New(WithRunners(plain, pausable))
+		assert.Nil(t, b.Pause(context.Background()))
+		assert.Nil(t, b.Resume(context.Background()))
+		assert.Equal(t, []string{"pause", "resume"}, calls)
+	})
+	t.Run("error", func(t *testing.T) {
+		pauseErr := errors.New("test")
+		var failingCalls []string
+		failing := pausableRunner{MockRunner: NewMockRunner(ctrl), calls: &failingCalls, err: pauseErr}
+		failing.EXPECT().Name().Return("failingRunner").AnyTimes()
+		b := New(WithRunners(plain, failing))
+		err := b.Pause(context.Background())
+		assert.ErrorIs(t, err, pauseErr)
+		assert.Contains(t, err.Error(), "pause runner failingRunner failed")
+		err = b.Resume(context.Background())
+		assert.ErrorIs(t, err, pauseErr)
+		assert.Contains(t, err.Error(), "resume runner failingRunner failed")
+	})
+}
@@ -0,0 +1,80 @@
+package bootstrap
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/pkg/errors"
+	"golang.org/x/exp/slog"
+)
+
+// runBeforeRun runs beforeRun, bounded by the before run timeout, retrying it if configured.
+// See WithBeforeRunRetry.
+func (b bootstrap) runBeforeRun(ctx context.Context, logger *slog.Logger) error {
+	attempts := b.beforeRunAttempts
+	if attempts < 1 {
+		attempts = 1
+	}
+	for attempt := 1; ; attempt++ {
+		beforeCtx, cancel := ctx, context.CancelFunc(func() {})
+		if b.beforeRunTimeout > 0 {
+			beforeCtx, cancel = context.WithTimeout(ctx, b.beforeRunTimeout)
+		}
+		err := runPhase(beforeCtx, "before run", b.beforeRun)
+		cancel()
+		if err == nil {
+			return nil
+		}
+		if attempts == 1 {
+			return err
+		}
+		if attempt == attempts || ctx.Err() != nil {
+			return errors.WithMessagef(err, "before run failed after %d attempts", attempt)
+		}
+		if logger.Enabled(slog.WarnLevel) {
+			logger.Warn(fmt.Sprintf("Before run failed, retrying in %s", b.beforeRunBackoff),
+				slog.Int("attempt", attempt), slog.Any(slog.ErrorKey, err))
+		}
+		select {
+		case <-b.clk().After(b.beforeRunBackoff):
+		case <-ctx.Done():
+			return errors.WithMessagef(err, "before run failed after %d attempts", attempt)
+		}
+	}
+}
+
+// runPhase runs fn with ctx, returning a *PhaseError if the deadline of ctx is exceeded before
+// fn returns. fn is then left running in the background.
+func runPhase(ctx context.Context, phase string, fn func(ctx context.Context) error) error {
+	if _, ok := ctx.Deadline(); !ok {
+		return fn(ctx)
+	}
+	done := make(chan error, 1)
+	go func() {
+		done <- fn(ctx)
+	}()
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		if !errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return <-done
+		}
+		return &PhaseError{Phase: phase, Err: ctx.Err()}
+	}
+}
+
+// awaitPhase waits for done to be closed, returning a *PhaseError if the deadline of ctx is
+// exceeded before. A cancelled ctx does not interrupt the wait, the phase is ended by the shutdown.
+func awaitPhase(ctx context.Context, phase string, done <-chan struct{}) error {
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		if !errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			<-done
+			return nil
+		}
+		return &PhaseError{Phase: phase, Err: ctx.Err()}
+	}
+}
@@ -0,0 +1,138 @@
+package bootstrap
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_runPhase(t *testing.T) {
+	testErr := errors.New("test")
+	assert.ErrorIs(t, runPhase(context.Background(), "test", func(ctx context.Context) error {
+		return testErr
+	}), testErr)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*10)
+	defer cancel()
+	err := runPhase(ctx, "test", func(ctx context.Context) error {
+		time.Sleep(time.Millisecond * 100)
+		return nil
+	})
+	var phaseErr *PhaseError
+	if assert.ErrorAs(t, err, &phaseErr) {
+		assert.Equal(t, "test", phaseErr.Phase)
+	}
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Equal(t, "bootstrap: test phase not finished: context deadline exceeded", err.Error())
+}
+
+func Test_awaitPhase(t *testing.T) {
+	done := make(chan struct{})
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	go func() {
+		time.Sleep(time.Millisecond * 10)
+		close(done)
+	}()
+	assert.Nil(t, awaitPhase(ctx, "test", done))
+	ctx, cancel = context.WithTimeout(context.Background(), time.Millisecond*10)
+	defer cancel()
+	assert.ErrorIs(t, awaitPhase(ctx, "test", make(chan struct{})), context.DeadlineExceeded)
+}
+
+func TestBootstrap_Run_phaseDeadline(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	r := NewMockRunner(ctrl)
+	r.EXPECT().Name().Return("testRunner").AnyTimes()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*20)
+	defer cancel()
+	err := New(WithRunners(r), WithBeforeRun(func(ctx context.Context) error {
+		time.Sleep(time.Millisecond * 200)
+		return nil
+	})).Run(ctx)
+	var phaseErr *PhaseError
+	if assert.ErrorAs(t, err, &phaseErr) {
+		assert.Equal(t, "before run", phaseErr.Phase)
+	}
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestBootstrap_Run_beforeRunTimeout(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	r := NewMockRunner(ctrl)
+	r.EXPECT().Name().Return("testRunner").AnyTimes()
+	start := time.Now()
+	err := New(WithRunners(r), WithBeforeRunTimeout(time.Millisecond*20), WithBeforeRun(func(ctx context.Context) error {
+		time.Sleep(time.Millisecond * 200)
+		return nil
+	})).Run(context.Background())
+	var phaseErr *PhaseError
+	if assert.ErrorAs(t, err, &phaseErr) {
+		assert.Equal(t, "before run", phaseErr.Phase)
+	}
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Less(t, time.Since(start), time.Millisecond*200)
+}
+
+func TestBootstrap_Run_beforeRunRetry(t *testing.T) {
+	t.Run("recovered", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		r := NewMockRunner(ctrl)
+		r.EXPECT().Name().Return("testRunner").AnyTimes()
+		r.EXPECT().Run(gomock.Any()).DoAndReturn(func(ctx context.Context) error {
+			<-ctx.Done()
+			return nil
+		})
+		r.EXPECT().Stop(gomock.Any()).Return(nil)
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		attempts := 0
+		b := New(WithRunners(r), WithBeforeRunRetry(3, time.Millisecond), WithBeforeRun(func(ctx context.Context) error {
+			attempts++
+			if attempts < 3 {
+				return errors.New("unavailable")
+			}
+			return nil
+		}), WithOnRun(func(ctx context.Context) error {
+			cancel()
+			return nil
+		}))
+		assert.Nil(t, b.Run(ctx))
+		assert.Equal(t, 3, attempts)
+	})
+	t.Run("failed", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		r := NewMockRunner(ctrl)
+		r.EXPECT().Name().Return("testRunner").AnyTimes()
+		beforeErr := errors.New("unavailable")
+		attempts := 0
+		err := New(WithRunners(r), WithBeforeRunRetry(3, time.Millisecond), WithBeforeRun(func(ctx context.Context) error {
+			attempts++
+			return beforeErr
+		})).Run(context.Background())
+		assert.ErrorIs(t, err, beforeErr)
+		assert.Contains(t, err.Error(), "before run failed after 3 attempts")
+		assert.Equal(t, 3, attempts)
+	})
+	t.Run("canceled", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		r := NewMockRunner(ctrl)
+		r.EXPECT().Name().Return("testRunner").AnyTimes()
+		ctx, cancel := context.WithCancel(context.Background())
+		beforeErr := errors.New("unavailable")
+		err := New(WithRunners(r), WithBeforeRunRetry(3, time.Minute), WithBeforeRun(func(ctx context.Context) error {
+			cancel()
+			return beforeErr
+		})).Run(ctx)
+		assert.ErrorIs(t, err, beforeErr)
+		assert.Contains(t, err.Error(), "before run failed after 1 attempts")
+	})
+}
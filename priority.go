@@ -0,0 +1,83 @@
+package bootstrap
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+
+	"github.com/yimi-go/runner"
+)
+
+// Prioritizer is implemented by runners ordered by priority, as a lightweight alternative to
+// dependencies. Runners start in ascending priority, each once the runners of lower priorities are
+// ready, see Readier, and stop in descending priority. Dependencies take precedence over priorities,
+// see WithDependency. Runners not implementing it have priority 0.
+type Prioritizer interface {
+	Priority() int
+}
+
+func priorityOf(r runner.Runner) int {
+	if p, ok := r.(Prioritizer); ok {
+		return p.Priority()
+	}
+	return 0
+}
+
+// lowerPriorities returns the entries of the run set with lower priorities than the one of e,
+// but the ones depending on it.
+func (l *lifecycle) lowerPriorities(e *runnerEntry) []*runnerEntry {
+	p := priorityOf(e.r)
+	l.entriesMux.Lock()
+	defer l.entriesMux.Unlock()
+	var lower []*runnerEntry
+	for _, le := range l.entries {
+		if priorityOf(le.r) < p && !l.b.dependsOn(le.r.Name(), e.r.Name()) {
+			lower = append(lower, le)
+		}
+	}
+	return lower
+}
+
+// awaitLowerPriorities waits for the runners of lower priorities than the one of e to be ready.
+func (l *lifecycle) awaitLowerPriorities(e *runnerEntry) error {
+	for _, le := range l.lowerPriorities(e) {
+		select {
+		case <-le.ready:
+		case <-l.egCtx.Done():
+			return l.egCtx.Err()
+		}
+		if !le.isReady.Load() {
+			return errors.Errorf("%s of lower priority exited before %s started", le.r.Name(), e.r.Name())
+		}
+	}
+	return nil
+}
+
+// awaitHigherPriorities waits for the runners of higher priorities than the one of r to be stopped,
+// but its dependencies, or ctx to be done.
+func (l *lifecycle) awaitHigherPriorities(ctx context.Context, r runner.Runner) {
+	p := priorityOf(r)
+	var names []string
+	l.entriesMux.Lock()
+	for _, e := range l.entries {
+		if priorityOf(e.r) > p && !l.b.dependsOn(r.Name(), e.r.Name()) {
+			names = append(names, e.r.Name())
+		}
+	}
+	l.entriesMux.Unlock()
+	var waits []chan struct{}
+	l.stopMux.Lock()
+	for _, name := range names {
+		if stopped, ok := l.stopRegistered[name]; ok {
+			waits = append(waits, stopped)
+		}
+	}
+	l.stopMux.Unlock()
+	for _, stopped := range waits {
+		select {
+		case <-stopped:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
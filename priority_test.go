@@ -0,0 +1,122 @@
+package bootstrap
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+type priorityRunner struct {
+	*MockRunner
+	priority int
+}
+
+func (r priorityRunner) Priority() int {
+	return r.priority
+}
+
+// readyPriorityRunner is a priorityRunner which is ready once its readierRunner is.
+type readyPriorityRunner struct {
+	readierRunner
+	priority int
+}
+
+func (r readyPriorityRunner) Priority() int {
+	return r.priority
+}
+
+func Test_priorityOf(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	r := NewMockRunner(ctrl)
+	assert.Equal(t, 0, priorityOf(r))
+	assert.Equal(t, 10, priorityOf(priorityRunner{MockRunner: r, priority: 10}))
+}
+
+func Test_bootstrap_sortEntries_priority(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	l := &lifecycle{}
+	var entries []*runnerEntry
+	for _, p := range []struct {
+		name     string
+		priority int
+	}{{"a", 20}, {"b", 0}, {"c", 10}, {"d", 30}, {"e", 0}} {
+		r := NewMockRunner(ctrl)
+		r.EXPECT().Name().Return(p.name).AnyTimes()
+		entries = append(entries, l.addEntry(priorityRunner{MockRunner: r, priority: p.priority}))
+	}
+	names := func(entries []*runnerEntry) []string {
+		var names []string
+		for _, e := range entries {
+			names = append(names, e.r.Name())
+		}
+		return names
+	}
+	b := bootstrap{}
+	assert.Equal(t, []string{"b", "e", "c", "a", "d"}, names(b.sortEntries(entries)))
+	// Within a dependency level, by priority.
+	for _, name := range []string{"a", "b", "c"} {
+		WithDependency(name, "d")(&b)
+	}
+	assert.Equal(t, []string{"e", "d", "b", "c", "a"}, names(b.sortEntries(entries)))
+}
+
+func TestBootstrap_Run_priority(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var mux sync.Mutex
+	var started, stopped []string
+	record := func(names *[]string, name string) {
+		mux.Lock()
+		defer mux.Unlock()
+		*names = append(*names, name)
+	}
+	b := New()
+	for _, p := range []struct {
+		name     string
+		priority int
+	}{{"p20", 20}, {"p0", 0}, {"p10", 10}} {
+		name := p.name
+		r := NewMockRunner(ctrl)
+		r.EXPECT().Name().Return(name).AnyTimes()
+		running, stop := make(chan struct{}), make(chan struct{})
+		r.EXPECT().Run(gomock.Any()).DoAndReturn(func(ctx context.Context) error {
+			record(&started, name)
+			close(running)
+			<-stop
+			return nil
+		})
+		r.EXPECT().Stop(gomock.Any()).DoAndReturn(func(ctx context.Context) error {
+			record(&stopped, name)
+			close(stop)
+			return nil
+		})
+		assert.Nil(t, b.AddRunner(readyPriorityRunner{
+			readierRunner: readierRunner{MockRunner: r, waitReady: func(ctx context.Context) error {
+				select {
+				case <-running:
+					return nil
+				case <-ctx.Done():
+					return ctx.Err()
+				}
+			}},
+			priority: p.priority,
+		}))
+	}
+	done := make(chan error)
+	go func() {
+		done <- b.Run(ctx)
+	}()
+	assert.Eventually(t, b.Ready, time.Second, time.Millisecond)
+	cancel()
+	assert.Nil(t, <-done)
+	assert.Equal(t, []string{"p0", "p10", "p20"}, started)
+	assert.Equal(t, []string{"p20", "p10", "p0"}, stopped)
+}
@@ -0,0 +1,107 @@
+//go:build prometheus
+
+package bootstrap
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// promMetrics is the Metrics backed by Prometheus collectors, see WithPrometheus.
+type promMetrics struct {
+	starts        *prometheus.CounterVec
+	stops         *prometheus.CounterVec
+	startDuration *prometheus.HistogramVec
+	stopDuration  *prometheus.HistogramVec
+}
+
+// WithPrometheus registers the runner metrics on reg, replacing the Metrics set by WithMetrics:
+//   - bootstrap_runners_total and bootstrap_runners_running gauges, of the ongoing Run;
+//   - bootstrap_runner_starts_total{runner} and bootstrap_runner_stops_total{runner,result}
+//     counters, result being "success" or "failure";
+//   - bootstrap_runner_start_duration_seconds{runner} and
+//     bootstrap_runner_stop_duration_seconds{runner} histograms.
+//
+// It panics if they are registered already, as prometheus.Registry.MustRegister does.
+// It is only built with the prometheus build tag, keeping the dependency optional.
+func WithPrometheus(reg *prometheus.Registry) Option {
+	return func(b *bootstrap) {
+		m := &promMetrics{
+			starts: prometheus.NewCounterVec(prometheus.CounterOpts{
+				Namespace: "bootstrap",
+				Name:      "runner_starts_total",
+				Help:      "Runners started.",
+			}, []string{"runner"}),
+			stops: prometheus.NewCounterVec(prometheus.CounterOpts{
+				Namespace: "bootstrap",
+				Name:      "runner_stops_total",
+				Help:      "Runners stopped, by result.",
+			}, []string{"runner", "result"}),
+			startDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+				Namespace: "bootstrap",
+				Name:      "runner_start_duration_seconds",
+				Help:      "Duration runners took to start.",
+			}, []string{"runner"}),
+			stopDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+				Namespace: "bootstrap",
+				Name:      "runner_stop_duration_seconds",
+				Help:      "Duration runners took to stop.",
+			}, []string{"runner"}),
+		}
+		s := b.state
+		reg.MustRegister(
+			prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+				Namespace: "bootstrap",
+				Name:      "runners_total",
+				Help:      "Runners of the ongoing Run.",
+			}, func() float64 {
+				total, _ := s.runnerCounts()
+				return float64(total)
+			}),
+			prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+				Namespace: "bootstrap",
+				Name:      "runners_running",
+				Help:      "Runners of the ongoing Run which are running.",
+			}, func() float64 {
+				_, running := s.runnerCounts()
+				return float64(running)
+			}),
+			m.starts, m.stops, m.startDuration, m.stopDuration,
+		)
+		b.metrics = m
+	}
+}
+
+func (m *promMetrics) ObserveStart(name string, d time.Duration) {
+	m.starts.WithLabelValues(name).Inc()
+	m.startDuration.WithLabelValues(name).Observe(d.Seconds())
+}
+
+func (m *promMetrics) ObserveStop(name string, d time.Duration, err error) {
+	result := "success"
+	if err != nil {
+		result = "failure"
+	}
+	m.stops.WithLabelValues(name, result).Inc()
+	m.stopDuration.WithLabelValues(name).Observe(d.Seconds())
+}
+
+// runnerCounts returns the number of runners of the ongoing Run, and of the running ones.
+func (s *runState) runnerCounts() (total, running int) {
+	s.mux.Lock()
+	l := s.current
+	s.mux.Unlock()
+	if l == nil {
+		return 0, 0
+	}
+	l.entriesMux.Lock()
+	defer l.entriesMux.Unlock()
+	for _, e := range l.entries {
+		total++
+		if e.started.Load() {
+			running++
+		}
+	}
+	return total, running
+}
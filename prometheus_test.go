@@ -0,0 +1,70 @@
+//go:build prometheus
+
+package bootstrap
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/yimi-go/runner"
+)
+
+func TestWithPrometheus(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	stopErr := errors.New("test")
+	var rs []runner.Runner
+	for _, name := range []string{"a", "b"} {
+		name := name
+		r := NewMockRunner(ctrl)
+		r.EXPECT().Name().Return(name).AnyTimes()
+		r.EXPECT().Run(gomock.Any()).DoAndReturn(func(ctx context.Context) error {
+			<-ctx.Done()
+			return nil
+		})
+		if name == "b" {
+			r.EXPECT().Stop(gomock.Any()).Return(stopErr)
+		} else {
+			r.EXPECT().Stop(gomock.Any()).Return(nil)
+		}
+		rs = append(rs, r)
+	}
+	reg := prometheus.NewRegistry()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	b := New(WithRunners(rs...), WithPrometheus(reg))
+	done := make(chan error)
+	go func() {
+		done <- b.Run(ctx)
+	}()
+	assert.Eventually(t, b.Ready, time.Second, time.Millisecond)
+	assert.Nil(t, testutil.GatherAndCompare(reg, strings.NewReader(`
+# HELP bootstrap_runners_running Runners of the ongoing Run which are running.
+# TYPE bootstrap_runners_running gauge
+bootstrap_runners_running 2
+# HELP bootstrap_runners_total Runners of the ongoing Run.
+# TYPE bootstrap_runners_total gauge
+bootstrap_runners_total 2
+`), "bootstrap_runners_total", "bootstrap_runners_running"))
+	m := b.(bootstrap).metrics.(*promMetrics)
+	assert.Equal(t, 2.0, testutil.ToFloat64(m.starts.WithLabelValues("a"))+testutil.ToFloat64(m.starts.WithLabelValues("b")))
+	cancel()
+	assert.Nil(t, <-done)
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.stops.WithLabelValues("a", "success")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.stops.WithLabelValues("b", "failure")))
+	assert.Equal(t, 2, testutil.CollectAndCount(m.stopDuration))
+	total, running := b.(bootstrap).state.runnerCounts()
+	assert.Zero(t, total)
+	assert.Zero(t, running)
+	assert.Panics(t, func() {
+		New(WithPrometheus(reg))
+	})
+}
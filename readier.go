@@ -0,0 +1,26 @@
+package bootstrap
+
+import (
+	"context"
+
+	"github.com/yimi-go/runner"
+)
+
+// Readier is an optional interface a runner.Runner may implement to signal its readiness,
+// e.g. once its server is listening. The bootstrap is started once all runners are ready,
+// runners not implementing Readier being ready as soon as they run.
+type Readier interface {
+	// WaitReady blocks until the runner is ready or ctx is done.
+	// It is called with the context of Run. An error fails the runner.
+	WaitReady(ctx context.Context) error
+}
+
+// awaitReady waits for r, running with ctx, to be ready.
+// Runners not implementing Readier are ready right away.
+func awaitReady(ctx context.Context, r runner.Runner) error {
+	rd, ok := r.(Readier)
+	if !ok {
+		return nil
+	}
+	return rd.WaitReady(ctx)
+}
@@ -0,0 +1,138 @@
+package bootstrap
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/exp/slog"
+)
+
+type readierRunner struct {
+	*MockRunner
+	waitReady func(ctx context.Context) error
+}
+
+func (r readierRunner) WaitReady(ctx context.Context) error {
+	return r.waitReady(ctx)
+}
+
+func Test_awaitReady(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	assert.Nil(t, awaitReady(context.Background(), NewMockRunner(ctrl)))
+	r := readierRunner{MockRunner: NewMockRunner(ctrl), waitReady: func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.ErrorIs(t, awaitReady(ctx, r), context.Canceled)
+}
+
+func TestBootstrap_Run_readier(t *testing.T) {
+	t.Run("started_after_ready", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		logBuf := &bytes.Buffer{}
+		ctx, cancel := context.WithCancel(bufLogCtx(context.Background(), logBuf))
+		defer cancel()
+		r := readierRunner{MockRunner: NewMockRunner(ctrl), waitReady: func(ctx context.Context) error {
+			time.Sleep(time.Millisecond * 50)
+			slog.Ctx(ctx).Info("runner ready")
+			return nil
+		}}
+		r.EXPECT().Name().Return("testRunner").AnyTimes()
+		r.EXPECT().Run(gomock.Any()).DoAndReturn(func(ctx context.Context) error {
+			<-ctx.Done()
+			return nil
+		})
+		r.EXPECT().Stop(gomock.Any()).Return(nil)
+		m := &fakeMetrics{}
+		b := New(WithRunners(r), WithMetrics(m))
+		done := make(chan error)
+		go func() {
+			done <- b.Run(ctx)
+		}()
+		assert.Eventually(t, b.Ready, time.Second, time.Millisecond)
+		cancel()
+		assert.Nil(t, <-done)
+		var msgs []any
+		for _, m := range printAndJson(t, logBuf) {
+			msgs = append(msgs, m[slog.MessageKey])
+		}
+		readyAt, startedAt := indexOf(msgs, "runner ready"), indexOf(msgs, "bootstrap started.")
+		assert.NotEqual(t, -1, readyAt)
+		assert.Greater(t, startedAt, readyAt)
+		// The start duration covers the time to get ready.
+		m.mux.Lock()
+		defer m.mux.Unlock()
+		assert.GreaterOrEqual(t, m.durations["testRunner"], time.Millisecond*50)
+	})
+	t.Run("not_ready", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		readyErr := errors.New("test")
+		r := readierRunner{MockRunner: NewMockRunner(ctrl), waitReady: func(ctx context.Context) error {
+			return readyErr
+		}}
+		r.EXPECT().Name().Return("testRunner").AnyTimes()
+		r.EXPECT().Run(gomock.Any()).DoAndReturn(func(ctx context.Context) error {
+			<-ctx.Done()
+			return nil
+		})
+		r.EXPECT().Stop(gomock.Any()).Return(nil).AnyTimes()
+		m := &fakeMetrics{}
+		err := New(WithRunners(r), WithMetrics(m)).Run(context.Background())
+		assert.Empty(t, m.starts)
+		assert.ErrorIs(t, err, readyErr)
+		assert.Contains(t, err.Error(), "starting testRunner failed: not ready")
+		var runnerErr *RunnerError
+		if assert.ErrorAs(t, err, &runnerErr) {
+			assert.Equal(t, RunnerPhaseStart, runnerErr.Phase)
+		}
+	})
+}
+
+func indexOf(s []any, v any) int {
+	for i, e := range s {
+		if e == v {
+			return i
+		}
+	}
+	return -1
+}
+
+func TestBootstrap_Run_startupProbe(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	newRunner := func(name string) *MockRunner {
+		r := NewMockRunner(ctrl)
+		r.EXPECT().Name().Return(name).AnyTimes()
+		r.EXPECT().Run(gomock.Any()).DoAndReturn(func(ctx context.Context) error {
+			<-ctx.Done()
+			return nil
+		})
+		r.EXPECT().Stop(gomock.Any()).Return(nil)
+		return r
+	}
+	slow := readierRunner{MockRunner: newRunner("slow"), waitReady: func(ctx context.Context) error {
+		time.Sleep(time.Millisecond * 20)
+		return nil
+	}}
+	var progress [][2]int
+	b := New(WithRunners(newRunner("a"), slow, newRunner("b")), WithStartupProbe(func(started, total int) {
+		progress = append(progress, [2]int{started, total})
+	}), WithOnRun(func(ctx context.Context) error {
+		cancel()
+		return nil
+	}))
+	assert.Nil(t, b.Run(ctx))
+	assert.Equal(t, [][2]int{{1, 3}, {2, 3}, {3, 3}}, progress)
+}
@@ -0,0 +1,86 @@
+package bootstrap
+
+import (
+	"github.com/yimi-go/shutdown"
+)
+
+// Reason is why a bootstrap stopped, see ShutdownReason.
+type Reason int
+
+const (
+	// ReasonNone means the bootstrap has not stopped, or did not run.
+	ReasonNone Reason = iota
+	// ReasonSignal is a shutdown triggered by a POSIX signal.
+	ReasonSignal
+	// ReasonContextCanceled is a shutdown triggered by the Run context being done.
+	ReasonContextCanceled
+	// ReasonRunnerError is a shutdown triggered by a runner failing to start or run.
+	ReasonRunnerError
+	// ReasonOnRunError is a shutdown triggered by onRun, or the afterReady hook, returning an error.
+	ReasonOnRunError
+	// ReasonRunTimeout is a shutdown triggered by the run timeout, see WithRunTimeout.
+	ReasonRunTimeout
+	// ReasonStopRequested is a shutdown triggered by Stop.
+	ReasonStopRequested
+	// ReasonOnRunCompleted is a shutdown triggered by onRun returning, see WithOnRunTriggersShutdown.
+	ReasonOnRunCompleted
+	// ReasonOther is a shutdown triggered otherwise, e.g. by a custom trigger.
+	ReasonOther
+)
+
+func (r Reason) String() string {
+	switch r {
+	case ReasonNone:
+		return "none"
+	case ReasonSignal:
+		return "signal"
+	case ReasonContextCanceled:
+		return "context canceled"
+	case ReasonRunnerError:
+		return "runner error"
+	case ReasonOnRunError:
+		return "onRun error"
+	case ReasonRunTimeout:
+		return "run timeout"
+	case ReasonStopRequested:
+		return "stop requested"
+	case ReasonOnRunCompleted:
+		return "onRun completed"
+	case ReasonOther:
+		return "other"
+	default:
+		return "unknown"
+	}
+}
+
+// ShutdownReason returns why the last Run stopped, as first detected.
+func (b bootstrap) ShutdownReason() Reason {
+	if b.state == nil {
+		return ReasonNone
+	}
+	return Reason(b.state.reason.Load())
+}
+
+// setReason sets the shutdown reason, unless one is set already.
+func (s *runState) setReason(r Reason) {
+	s.reason.CompareAndSwap(int32(ReasonNone), int32(r))
+}
+
+// reasonOf returns the shutdown reason of event.
+func (l *lifecycle) reasonOf(event shutdown.Event) Reason {
+	switch causeOf(event) {
+	case CauseSignal:
+		return ReasonSignal
+	case CauseContextDone:
+		if l.b.runTimedOut != nil && l.b.runTimedOut() {
+			return ReasonRunTimeout
+		}
+		return ReasonContextCanceled
+	case CauseStopRequested:
+		return ReasonStopRequested
+	case CauseOnRunCompleted:
+		return ReasonOnRunCompleted
+	default:
+		return ReasonOther
+	}
+}
@@ -0,0 +1,150 @@
+package bootstrap
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/exp/slog"
+
+	"github.com/yimi-go/shutdown"
+)
+
+func TestReason_String(t *testing.T) {
+	tests := map[Reason]string{
+		ReasonNone:            "none",
+		ReasonSignal:          "signal",
+		ReasonContextCanceled: "context canceled",
+		ReasonRunnerError:     "runner error",
+		ReasonOnRunError:      "onRun error",
+		ReasonRunTimeout:      "run timeout",
+		ReasonStopRequested:   "stop requested",
+		ReasonOnRunCompleted:  "onRun completed",
+		ReasonOther:           "other",
+		Reason(-1):            "unknown",
+	}
+	for r, want := range tests {
+		assert.Equal(t, want, r.String())
+	}
+}
+
+func TestBootstrap_ShutdownReason(t *testing.T) {
+	newRunner := func(ctrl *gomock.Controller, runErr error) *MockRunner {
+		r := NewMockRunner(ctrl)
+		r.EXPECT().Name().Return("testRunner").AnyTimes()
+		stopped := make(chan struct{})
+		r.EXPECT().Run(gomock.Any()).DoAndReturn(func(ctx context.Context) error {
+			if runErr != nil {
+				return runErr
+			}
+			<-stopped
+			return nil
+		})
+		r.EXPECT().Stop(gomock.Any()).DoAndReturn(func(ctx context.Context) error {
+			close(stopped)
+			return nil
+		}).AnyTimes()
+		return r
+	}
+	t.Run("signal", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		logBuf := &bytes.Buffer{}
+		tr := NewMockTrigger(ctrl)
+		tr.EXPECT().Wait(gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, c shutdown.Controller) error {
+			c.HandleShutdown(ctx, shutdown.EventFunc(func() string {
+				return "received signal: terminated"
+			}))
+			return nil
+		})
+		b := New(WithRunners(newRunner(ctrl, nil)), WithTriggers(tr))
+		assert.Equal(t, ReasonNone, b.ShutdownReason())
+		assert.Nil(t, b.Run(bufLogCtx(context.Background(), logBuf)))
+		assert.Equal(t, ReasonSignal, b.ShutdownReason())
+		mps := printAndJson(t, logBuf)
+		summary := mps[len(mps)-1]
+		assert.Equal(t, "bootstrap stopped.", summary[slog.MessageKey])
+		assert.Equal(t, "signal", summary["shutdown_reason"])
+	})
+	t.Run("runner_error", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		runErr := errors.New("test")
+		b := New(WithRunners(newRunner(ctrl, runErr)))
+		assert.ErrorIs(t, b.Run(context.Background()), runErr)
+		assert.Equal(t, ReasonRunnerError, b.ShutdownReason())
+	})
+	t.Run("onRun_error", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		onRunErr := errors.New("test")
+		b := New(WithRunners(newRunner(ctrl, nil)), WithOnRun(func(ctx context.Context) error {
+			return onRunErr
+		}))
+		assert.ErrorIs(t, b.Run(context.Background()), onRunErr)
+		assert.Equal(t, ReasonOnRunError, b.ShutdownReason())
+	})
+	t.Run("not_ready", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		readyErr := errors.New("test")
+		r := readierRunner{MockRunner: newRunner(ctrl, nil), waitReady: func(ctx context.Context) error {
+			return readyErr
+		}}
+		b := New(WithRunners(r))
+		assert.ErrorIs(t, b.Run(context.Background()), readyErr)
+		assert.Equal(t, ReasonRunnerError, b.ShutdownReason())
+	})
+	t.Run("afterReady_error", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		hookErr := errors.New("test")
+		b := New(WithRunners(newRunner(ctrl, nil)), WithAfterReady(func(ctx context.Context) error {
+			return hookErr
+		}))
+		assert.ErrorIs(t, b.Run(context.Background()), hookErr)
+		assert.Equal(t, ReasonOnRunError, b.ShutdownReason())
+	})
+	t.Run("context", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		ctx, cancel := context.WithCancel(context.Background())
+		b := New(WithRunners(newRunner(ctrl, nil)))
+		done := make(chan error)
+		go func() {
+			done <- b.Run(ctx)
+		}()
+		assert.Eventually(t, b.Ready, time.Second, time.Millisecond)
+		cancel()
+		assert.Nil(t, <-done)
+		assert.Equal(t, ReasonContextCanceled, b.ShutdownReason())
+	})
+	t.Run("run_timeout", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		b := New(WithRunners(newRunner(ctrl, nil)), WithRunTimeout(time.Millisecond*20))
+		assert.ErrorIs(t, b.Run(context.Background()), ErrRunTimeout)
+		assert.Equal(t, ReasonRunTimeout, b.ShutdownReason())
+	})
+	t.Run("stop_requested", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		b := New(WithRunners(newRunner(ctrl, nil)))
+		done := make(chan error)
+		go func() {
+			done <- b.Run(context.Background())
+		}()
+		assert.Eventually(t, b.Ready, time.Second, time.Millisecond)
+		assert.Nil(t, b.Stop(context.Background()))
+		assert.Nil(t, <-done)
+		assert.Equal(t, ReasonStopRequested, b.ShutdownReason())
+	})
+	t.Run("nil_state", func(t *testing.T) {
+		var b bootstrap
+		assert.Equal(t, ReasonNone, b.ShutdownReason())
+	})
+}
@@ -0,0 +1,80 @@
+package bootstrap
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/pkg/errors"
+	"golang.org/x/exp/slog"
+
+	"github.com/yimi-go/runner"
+)
+
+// Requirement is a precondition of the environment a runner needs to start.
+type Requirement struct {
+	// Name describes the requirement, e.g. "env DATABASE_URL".
+	Name string
+	// Check returns a non-nil error if the requirement is not met.
+	Check func() error
+	// Skip makes the runner skipped if the requirement is not met, instead of failing the bootstrap.
+	Skip bool
+}
+
+// EnvRequirement requires the environment variable key to be set.
+func EnvRequirement(key string) Requirement {
+	return Requirement{
+		Name: "env " + key,
+		Check: func() error {
+			if _, ok := os.LookupEnv(key); !ok {
+				return errors.New("not set")
+			}
+			return nil
+		},
+	}
+}
+
+// Requirer is an optional interface a runner.Runner may implement to declare the requirements
+// checked before it starts.
+type Requirer interface {
+	Requirements() []Requirement
+}
+
+// RequirementError is returned by Run if a runner requirement is not met.
+type RequirementError struct {
+	Runner      string
+	Requirement string
+	Err         error
+}
+
+func (e *RequirementError) Error() string {
+	return fmt.Sprintf("runner %s requirement %s not met: %v", e.Runner, e.Requirement, e.Err)
+}
+
+func (e *RequirementError) Unwrap() error {
+	return e.Err
+}
+
+// checkRequirements checks the requirements of r.
+// It reports whether r is to be skipped, or returns a *RequirementError if it is to fail.
+func (l *lifecycle) checkRequirements(r runner.Runner) (skip bool, err error) {
+	rq, ok := r.(Requirer)
+	if !ok {
+		return false, nil
+	}
+	for _, req := range rq.Requirements() {
+		checkErr := req.Check()
+		if checkErr == nil {
+			continue
+		}
+		reqErr := &RequirementError{Runner: r.Name(), Requirement: req.Name, Err: checkErr}
+		if !req.Skip {
+			return false, reqErr
+		}
+		if l.logger.Enabled(slog.WarnLevel) {
+			l.logger.Warn(fmt.Sprintf("Runner skipped: %s", r.Name()), slog.String("cause", reqErr.Error()))
+		}
+		l.b.warn(r.Name(), "skipped", reqErr)
+		skip = true
+	}
+	return skip, nil
+}
@@ -0,0 +1,77 @@
+package bootstrap
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+type requirerRunner struct {
+	*MockRunner
+	requirements []Requirement
+}
+
+func (r requirerRunner) Requirements() []Requirement {
+	return r.requirements
+}
+
+func TestEnvRequirement(t *testing.T) {
+	req := EnvRequirement("BOOTSTRAP_TEST_ENV")
+	assert.Equal(t, "env BOOTSTRAP_TEST_ENV", req.Name)
+	assert.NotNil(t, req.Check())
+	t.Setenv("BOOTSTRAP_TEST_ENV", "")
+	assert.Nil(t, req.Check())
+}
+
+func TestBootstrap_Run_requirements(t *testing.T) {
+	t.Run("missing", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		r := requirerRunner{
+			MockRunner:   NewMockRunner(ctrl),
+			requirements: []Requirement{EnvRequirement("BOOTSTRAP_TEST_MISSING_ENV")},
+		}
+		r.EXPECT().Name().Return("testRunner").AnyTimes()
+		r.EXPECT().Stop(gomock.Any()).Return(nil).AnyTimes()
+		err := New(WithRunners(r)).Run(context.Background())
+		var reqErr *RequirementError
+		if assert.ErrorAs(t, err, &reqErr) {
+			assert.Equal(t, "testRunner", reqErr.Runner)
+			assert.Equal(t, "env BOOTSTRAP_TEST_MISSING_ENV", reqErr.Requirement)
+		}
+		assert.Contains(t, err.Error(), "runner testRunner requirement env BOOTSTRAP_TEST_MISSING_ENV not met")
+	})
+	t.Run("skip", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		req := EnvRequirement("BOOTSTRAP_TEST_MISSING_ENV")
+		req.Skip = true
+		skipped := requirerRunner{MockRunner: NewMockRunner(ctrl), requirements: []Requirement{req}}
+		skipped.EXPECT().Name().Return("skipped").AnyTimes()
+		skipped.EXPECT().Stop(gomock.Any()).Return(nil).AnyTimes()
+		r := NewMockRunner(ctrl)
+		r.EXPECT().Name().Return("testRunner").AnyTimes()
+		r.EXPECT().Run(gomock.Any()).DoAndReturn(func(ctx context.Context) error {
+			<-ctx.Done()
+			return nil
+		})
+		r.EXPECT().Stop(gomock.Any()).Return(nil)
+		b := New(WithRunners(skipped, r))
+		done := make(chan error)
+		go func() {
+			done <- b.Run(ctx)
+		}()
+		assert.Eventually(t, b.Ready, time.Second, time.Millisecond)
+		cancel()
+		assert.Nil(t, <-done)
+		warnings := b.Warnings()
+		if assert.Len(t, warnings, 1) {
+			assert.Equal(t, "skipped", warnings[0].Runner)
+		}
+	})
+}
@@ -0,0 +1,42 @@
+package bootstrap
+
+import (
+	"context"
+	"time"
+)
+
+// RunResult is the outcome of a Run, see RunWithResult.
+type RunResult struct {
+	// StartedAt is when Run was called, StoppedAt when it returned.
+	StartedAt time.Time
+	StoppedAt time.Time
+	// Reason is why it stopped, see ShutdownReason.
+	Reason Reason
+	// Errors maps runner names to their last Run or Stop error, see LastError.
+	Errors map[string]error
+}
+
+// RunWithResult runs the bootstrap as Run does, and returns the outcome of the run along with
+// the error Run would have returned.
+func (b bootstrap) RunWithResult(ctx context.Context) (RunResult, error) {
+	result := RunResult{StartedAt: b.clk().Now()}
+	err := b.run(ctx)
+	result.StoppedAt = b.clk().Now()
+	result.Reason = b.ShutdownReason()
+	result.Errors = b.state.runnerErrors()
+	return result, err
+}
+
+// runnerErrors returns a copy of the last errors of the runners.
+func (s *runState) runnerErrors() map[string]error {
+	errs := map[string]error{}
+	if s == nil {
+		return errs
+	}
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	for name, err := range s.lastErrors {
+		errs[name] = err
+	}
+	return errs
+}
@@ -0,0 +1,47 @@
+package bootstrap
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBootstrap_RunWithResult(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	runErr := errors.New("test")
+	failing := NewMockRunner(ctrl)
+	failing.EXPECT().Name().Return("failing").AnyTimes()
+	failing.EXPECT().Run(gomock.Any()).DoAndReturn(func(ctx context.Context) error {
+		time.Sleep(time.Millisecond * 20)
+		return runErr
+	})
+	failing.EXPECT().Stop(gomock.Any()).Return(nil).AnyTimes()
+	healthy := NewMockRunner(ctrl)
+	healthy.EXPECT().Name().Return("healthy").AnyTimes()
+	healthy.EXPECT().Run(gomock.Any()).DoAndReturn(func(ctx context.Context) error {
+		<-ctx.Done()
+		return nil
+	})
+	healthy.EXPECT().Stop(gomock.Any()).Return(nil).AnyTimes()
+	before := time.Now()
+	result, err := New(WithRunners(failing, healthy)).RunWithResult(context.Background())
+	after := time.Now()
+	assert.ErrorIs(t, err, runErr)
+	assert.False(t, result.StartedAt.Before(before))
+	assert.False(t, result.StoppedAt.After(after))
+	assert.GreaterOrEqual(t, result.StoppedAt.Sub(result.StartedAt), time.Millisecond*20)
+	assert.Equal(t, ReasonRunnerError, result.Reason)
+	if assert.Len(t, result.Errors, 1) {
+		assert.ErrorIs(t, result.Errors["failing"], runErr)
+	}
+}
+
+func Test_runState_runnerErrors(t *testing.T) {
+	var s *runState
+	assert.Empty(t, s.runnerErrors())
+}
@@ -0,0 +1,904 @@
+package bootstrap
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.opentelemetry.io/otel/trace"
+	"golang.org/x/exp/slog"
+	"golang.org/x/sync/errgroup"
+	"golang.org/x/sync/semaphore"
+
+	"github.com/yimi-go/runner"
+	"github.com/yimi-go/shutdown"
+)
+
+// startRunnerHook, if set, is called by each runner goroutine first. It is meant for tests.
+var startRunnerHook func(r runner.Runner)
+
+// runnerEntry is a runner in the run set of a lifecycle.
+type runnerEntry struct {
+	r runner.Runner
+	// started reports whether the runner has started and not failed.
+	started atomic.Bool
+	// ready is closed once the runner is ready or has returned, isReady tells which.
+	ready   chan struct{}
+	isReady atomic.Bool
+	// skipped tells whether the runner was skipped, see Requirement.Skip.
+	skipped   atomic.Bool
+	readyOnce sync.Once
+	slotOnce  sync.Once
+	// awaited tells whether the startup waits for the runner, signaled whether it was signaled.
+	awaited  bool
+	signaled atomic.Bool
+}
+
+// markReady closes the ready channel of e. It reports whether this call closed it.
+func (e *runnerEntry) markReady(ready bool) (marked bool) {
+	e.readyOnce.Do(func() {
+		e.isReady.Store(ready)
+		close(e.ready)
+		marked = true
+	})
+	return
+}
+
+// lifecycle is the state of one Run of a bootstrap.
+type lifecycle struct {
+	b      bootstrap
+	logger *slog.Logger
+	jnl    *journal
+	bootAt time.Time
+
+	eg    *errgroup.Group
+	egCtx context.Context
+	// cancelRun cancels the parent of egCtx, with the cause of the end of the run.
+	cancelRun context.CancelCauseFunc
+	// runnersCtx is the context the runners run with, done the in-flight grace after egCtx.
+	runnersCtx context.Context
+	// triggerCtx is the context the shutdown triggers wait with.
+	// It is cancelled once shutdown completes, releasing triggers that did not fire.
+	triggerCtx     context.Context
+	cancelTriggers context.CancelFunc
+
+	onRunCtx    context.Context
+	cancelOnRun context.CancelFunc
+	onRunDone   chan struct{}
+	// readyFlipped is closed once the readiness is flipped, see bootstrap.markReady.
+	readyFlipped <-chan struct{}
+
+	waitStart sync.WaitGroup
+	// startErrs buffers the startup failures collected with the BestEffort policy, until startupDone.
+	// A runner fails its startup once at most, and it is sized to the runners of the startup: their
+	// failures are collected without locking. Those of the runners added meanwhile may overflow to
+	// startOverflow, under startMux. startSending counts the failures being collected.
+	startErrs     chan error
+	startOverflow []error
+	startMux      sync.Mutex
+	startupDone   atomic.Bool
+	startSending  atomic.Int32
+	// startupTotal is the number of runners the startup waits for, startupReady the ones ready,
+	// reported to the startup probe under probeMux. The shutdown probe is called under it too.
+	startupTotal int
+	startupReady int
+	probeMux     sync.Mutex
+	// startSem limits the runners in their startup phase, if configured.
+	startSem *semaphore.Weighted
+	// runSem limits the runners running at once, if configured. The internal goroutines of the
+	// group are not limited.
+	runSem *semaphore.Weighted
+	// entries is the run set, in start order. Runners added while running are appended.
+	entries    []*runnerEntry
+	entriesMux sync.Mutex
+
+	shutdownOnce sync.Once
+	// event is the event of the first stop callback.
+	event shutdown.Event
+	// shutdownTimeout is the timeout set by SetShutdownTimeout when the shutdown began.
+	shutdownTimeout time.Duration
+	timeoutOnce     sync.Once
+	stopTotal       atomic.Int32
+	stoppedCount    atomic.Int32
+	stopErrs        errCollector
+	stopMux         sync.Mutex
+	// stopRegistered records the runner names whose stop callbacks have been registered,
+	// with a channel closed once the runner is stopped.
+	stopRegistered map[string]chan struct{}
+	// dynamicStops holds the stop callbacks of the runners added while running, invoked by the
+	// dynamic stop callback. dynamicClosed is set once it has taken them, under dynamicMux.
+	// dynamicStopped is set once they are stopped, see registerDynamicStop.
+	dynamicStops   []shutdown.Callback
+	dynamicClosed  bool
+	dynamicMux     sync.Mutex
+	dynamicStopped atomic.Bool
+	// stopSem limits the runners stopping at once, if configured.
+	stopSem *semaphore.Weighted
+	// stopping records the runner names whose Stop is in flight.
+	stopping map[string]struct{}
+	// timedOut records the runner names still stopping when the shutdown timed out.
+	timedOut []string
+	// hungStopGrace is hungStopGrace as of the start of the run.
+	hungStopGrace time.Duration
+
+	// job tells whether the run has no runners, running onRun or beforeRun only.
+	job bool
+
+	// failure is the first failure which shut down the bootstrap gracefully, if any.
+	failure atomic.Pointer[error]
+
+	// done is closed when Run is about to return.
+	done chan struct{}
+}
+
+func newLifecycle(ctx context.Context, b bootstrap, logger *slog.Logger, jnl *journal, bootAt time.Time) *lifecycle {
+	l := &lifecycle{
+		b:         b,
+		logger:    logger,
+		jnl:       jnl,
+		bootAt:    bootAt,
+		onRunDone: make(chan struct{}),
+		done:      make(chan struct{}),
+
+		stopRegistered: map[string]chan struct{}{},
+		dynamicClosed:  b.runnerWindow == UntilRun,
+		stopping:       map[string]struct{}{},
+		hungStopGrace:  hungStopGrace,
+	}
+	l.dynamicStopped.Store(l.dynamicClosed)
+	if b.maxConcurrentStart > 0 {
+		l.startSem = semaphore.NewWeighted(int64(b.maxConcurrentStart))
+	}
+	if b.stopConcurrency > 0 {
+		l.stopSem = semaphore.NewWeighted(int64(b.stopConcurrency))
+	}
+	if b.groupConcurrency > 0 {
+		l.runSem = semaphore.NewWeighted(int64(b.groupConcurrency))
+	}
+	runCtx, cancelRun := context.WithCancelCause(ctx)
+	l.cancelRun = cancelRun
+	l.eg, l.egCtx = errgroup.WithContext(runCtx)
+	l.runnersCtx = l.egCtx
+	if b.inFlightGrace > 0 {
+		// Not cancelled with the run context, but still bound by its deadline.
+		runnersCtx, cancelRunners := detachKeepDeadline(l.egCtx)
+		l.runnersCtx = runnersCtx
+		go func() {
+			defer cancelRunners()
+			select {
+			case <-l.egCtx.Done():
+			case <-l.done:
+				return
+			}
+			// Let the runners finish their in-flight work.
+			select {
+			case <-b.clk().After(b.inFlightGrace):
+			case <-l.done:
+			}
+		}()
+	}
+	l.triggerCtx, l.cancelTriggers = context.WithCancel(l.egCtx)
+	if b.onRunLifecycle == OnRunCancel {
+		l.onRunCtx, l.cancelOnRun = context.WithCancel(l.egCtx)
+	} else {
+		l.onRunCtx, l.cancelOnRun = context.WithCancel(detach(l.egCtx))
+	}
+	return l
+}
+
+// beginShutdown runs once, when the first stop callback is invoked.
+// Other callbacks block until it completes.
+func (l *lifecycle) beginShutdown(ctx context.Context, event shutdown.Event) {
+	l.shutdownOnce.Do(func() {
+		l.event = event
+		l.b.state.setShutdownEvent(event)
+		l.b.state.setReason(l.reasonOf(event))
+		l.b.state.markStopping()
+		if l.b.forceExit {
+			l.watchForceExit()
+		}
+		if deadline, ok := ctx.Deadline(); ok {
+			go l.watchHungStops(deadline)
+		}
+		if l.b.drainDelay > 0 {
+			// Let the load balancers observe the readiness flip before stopping.
+			select {
+			case <-l.b.clk().After(l.b.drainDelay):
+			case <-ctx.Done():
+			}
+		}
+		switch l.b.onRunLifecycle {
+		case OnRunCancel:
+			l.cancelOnRun()
+		case OnRunComplete:
+			select {
+			case <-l.onRunDone:
+			case <-ctx.Done():
+			}
+			l.cancelOnRun()
+		}
+		l.runBeforeStop(ctx)
+	})
+}
+
+// endShutdown runs after each stop callback. It completes the shutdown once all runners stopped.
+func (l *lifecycle) endShutdown() {
+	if l.countStopped() != l.stopTotal.Load() || !l.dynamicStopped.Load() {
+		return
+	}
+	l.completeShutdown()
+}
+
+// completeShutdown ends the run once all runners stopped.
+func (l *lifecycle) completeShutdown() {
+	if l.b.onRunLifecycle == OnRunDrain {
+		l.cancelOnRun()
+	}
+	if l.event != nil {
+		l.cancelRun(&ShutdownError{Cause: causeOf(l.event), Reason: l.event.Reason()})
+	}
+	l.cancelTriggers()
+}
+
+// countStopped counts a stopped runner, reporting it to the shutdown probe if any, see
+// WithShutdownProbe. It returns the number of stopped runners.
+func (l *lifecycle) countStopped() int32 {
+	if l.b.shutdownProbe == nil {
+		return l.stoppedCount.Add(1)
+	}
+	l.probeMux.Lock()
+	defer l.probeMux.Unlock()
+	stopped := l.stoppedCount.Add(1)
+	l.b.shutdownProbe(int(stopped), int(l.stopTotal.Load()))
+	return stopped
+}
+
+// registerStop registers the shutdown callback stopping r, unless one has been registered for the
+// runner name already.
+func (l *lifecycle) registerStop(r runner.Runner) {
+	if l.reserveStop(r.Name()) {
+		l.b.gs.AddShutdownCallback(l.stopCallback(r))
+	}
+}
+
+// reserveStop records that the runner named name is stopped on shutdown.
+// It reports false if it is already.
+func (l *lifecycle) reserveStop(name string) bool {
+	l.stopMux.Lock()
+	defer l.stopMux.Unlock()
+	if _, ok := l.stopRegistered[name]; ok {
+		return false
+	}
+	l.stopRegistered[name] = make(chan struct{})
+	l.stopTotal.Add(1)
+	return true
+}
+
+// stopCallback creates the shutdown callback stopping r.
+// The callback stops r only the first time it is called.
+func (l *lifecycle) stopCallback(r runner.Runner) shutdown.Callback {
+	once := &sync.Once{}
+	return shutdown.CallbackFunc(func(ctx context.Context, event shutdown.Event) (err error) {
+		if l.finished() {
+			return nil
+		}
+		once.Do(func() {
+			err = l.stop(ctx, event, r)
+		})
+		return
+	})
+}
+
+// stop stops r on shutdown.
+func (l *lifecycle) stop(ctx context.Context, event shutdown.Event, r runner.Runner) error {
+	ctx, cancel := l.shutdownContext(ctx, event)
+	defer cancel()
+	defer l.endShutdown()
+	defer l.markStopped(r.Name())
+	l.beginShutdown(ctx, event)
+	l.logger.Debug(fmt.Sprintf("Runner %s awaits its turn to stop", r.Name()))
+	l.b.waitMinUptime(ctx, l.bootAt)
+	l.awaitDependents(ctx, r.Name())
+	l.awaitLowerPhases(ctx, r.Name())
+	if l.b.hasStopOrder() {
+		// The stop order replaces the priorities.
+		l.awaitStopOrder(ctx, r.Name())
+	} else {
+		l.awaitHigherPriorities(ctx, r)
+	}
+	if l.logger.Enabled(slog.InfoLevel) && l.b.sampled(r.Name(), LogEventStop) {
+		l.logger.Info(fmt.Sprintf("Stopping runner: %s, cause: %s", r.Name(), event.Reason()), l.b.labelArgs(r.Name())...)
+	}
+	l.jnl.record(journalRunnerStop, r.Name(), nil, false)
+	ctx, span := l.b.startChildSpan(ctx, l.egCtx, "runner.stop/"+r.Name())
+	stopAt := l.b.clk().Now()
+	l.trackStop(r.Name(), true)
+	err := l.stopRunner(ctx, r)
+	l.trackStop(r.Name(), false)
+	l.logger.Debug(fmt.Sprintf("Stop of runner %s returned after %s", r.Name(), l.b.since(stopAt)))
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		l.stopTimedOut(r.Name())
+	}
+	l.b.observeStop(r.Name(), l.b.since(stopAt), err)
+	endSpan(span, err)
+	if l.b.onRunnerStop != nil {
+		l.b.onRunnerStop(ctx, r, err)
+	}
+	l.jnl.record(journalRunnerStopped, r.Name(), err, true)
+	if err != nil {
+		l.b.state.recordError(r.Name(), err)
+		l.b.warn(r.Name(), "stop failed", err)
+		err = &RunnerError{Name: r.Name(), Phase: RunnerPhaseStop, Err: err}
+		if l.b.returnStopErrors {
+			l.stopErrs.add(err)
+		}
+		return err
+	}
+	if l.logger.Enabled(slog.InfoLevel) && l.b.sampled(r.Name(), LogEventStopped) {
+		l.logger.Info(fmt.Sprintf("Runner stoped: %s", r.Name()), l.b.labelArgs(r.Name())...)
+	}
+	return nil
+}
+
+// stopRunner stops r, within the stop concurrency if configured.
+func (l *lifecycle) stopRunner(ctx context.Context, r runner.Runner) error {
+	if l.stopSem != nil {
+		if err := l.stopSem.Acquire(ctx, 1); err != nil {
+			return err
+		}
+		defer l.stopSem.Release(1)
+	}
+	return l.b.stopRunner(ctx, r)
+}
+
+// hungStopGrace is how long after the shutdown deadline Stop calls are considered hung.
+var hungStopGrace = time.Millisecond * 100
+
+// trackStop records whether the Stop of the runner named name is in flight.
+func (l *lifecycle) trackStop(name string, inFlight bool) {
+	l.stopMux.Lock()
+	defer l.stopMux.Unlock()
+	if inFlight {
+		l.stopping[name] = struct{}{}
+	} else {
+		delete(l.stopping, name)
+	}
+}
+
+// watchHungStops warns about the Stop calls still in flight shortly after the shutdown deadline,
+// unless Run returns first. Their goroutines may leak.
+func (l *lifecycle) watchHungStops(deadline time.Time) {
+	timer := l.b.clk().NewTimer(deadline.Sub(l.b.clk().Now()) + l.hungStopGrace)
+	defer timer.Stop()
+	select {
+	case <-timer.C():
+	case <-l.done:
+		return
+	}
+	l.stopMux.Lock()
+	names := make([]string, 0, len(l.stopping))
+	for name := range l.stopping {
+		names = append(names, name)
+	}
+	l.stopMux.Unlock()
+	if len(names) == 0 {
+		return
+	}
+	sort.Strings(names)
+	if l.logger.Enabled(slog.WarnLevel) {
+		l.logger.Warn(fmt.Sprintf("Runners did not return from Stop after the shutdown timeout: %s",
+			strings.Join(names, ", ")), slog.Int("goroutines", runtime.NumGoroutine()))
+	}
+	for _, name := range names {
+		l.b.warn(name, "stop did not return after the shutdown timeout", nil)
+	}
+}
+
+// addEntry appends r to the run set.
+func (l *lifecycle) addEntry(r runner.Runner) *runnerEntry {
+	e := &runnerEntry{r: r, ready: make(chan struct{})}
+	l.entriesMux.Lock()
+	l.entries = append(l.entries, e)
+	l.entriesMux.Unlock()
+	return e
+}
+
+// hasRunner reports whether a runner of the run set is named name.
+func (l *lifecycle) hasRunner(name string) bool {
+	l.entriesMux.Lock()
+	defer l.entriesMux.Unlock()
+	for _, e := range l.entries {
+		if e.r.Name() == name {
+			return true
+		}
+	}
+	return false
+}
+
+// runners returns the runners of the run set.
+func (l *lifecycle) runners() []runner.Runner {
+	l.entriesMux.Lock()
+	defer l.entriesMux.Unlock()
+	rs := make([]runner.Runner, 0, len(l.entries))
+	for _, e := range l.entries {
+		rs = append(rs, e.r)
+	}
+	return rs
+}
+
+// startRunner starts the runner of e in the errgroup.
+// If await is set, the startup of the bootstrap waits for it.
+func (l *lifecycle) startRunner(e *runnerEntry, await bool) {
+	r := e.r
+	if await {
+		e.awaited = true
+		l.waitStart.Add(1)
+	}
+	startAt := l.b.clk().Now()
+	l.goRun(func() error {
+		if startRunnerHook != nil {
+			startRunnerHook(r)
+		}
+		if err := l.awaitDependencies(e); err != nil {
+			e.markReady(false)
+			l.signalStart(e)
+			return l.startFailed(err)
+		}
+		if err := l.awaitLowerPriorities(e); err != nil {
+			e.markReady(false)
+			l.signalStart(e)
+			return l.startFailed(err)
+		}
+		if skip, err := l.checkRequirements(r); skip || err != nil {
+			e.skipped.Store(skip)
+			e.markReady(false)
+			l.signalStart(e)
+			return err
+		}
+		if l.runSem != nil {
+			// Queued until a running runner returns.
+			if err := l.runSem.Acquire(l.egCtx, 1); err != nil {
+				e.markReady(false)
+				l.signalStart(e)
+				return nil
+			}
+			defer l.runSem.Release(1)
+		}
+		if l.startSem != nil {
+			if err := l.startSem.Acquire(l.egCtx, 1); err != nil {
+				// The errgroup is done already, do not start.
+				e.markReady(false)
+				l.signalStart(e)
+				return nil
+			}
+		}
+		if !l.b.sortedStartLogs && l.logger.Enabled(slog.InfoLevel) && l.b.sampled(r.Name(), LogEventStart) {
+			l.logger.Info(fmt.Sprintf("Starting runner: %s", r.Name()), l.b.labelArgs(r.Name())...)
+		}
+		l.jnl.record(journalRunnerStart, r.Name(), nil, false)
+		e.started.Store(true)
+		_, readier := r.(Readier)
+		if !readier {
+			l.signalReady(e)
+		}
+		// The start span ends once the runner is ready, the run span once it returns.
+		_, startSpan := l.b.startSpan(l.runnersCtx, "runner.start/"+r.Name())
+		runCtx, runSpan := l.b.startSpan(l.runnersCtx, "runner.run/"+r.Name())
+		// Runners logging with slog.Ctx get their name and labels attached.
+		runCtx = slog.NewContext(runCtx, slog.Ctx(runCtx).With(append([]any{slog.String("runner", r.Name())}, l.b.labelArgs(r.Name())...)...))
+		if fn := l.b.runnerContexts[r.Name()]; fn != nil {
+			if derived := fn(runCtx); derived != nil {
+				runCtx = derived
+			}
+		}
+		if l.b.onRunnerStart != nil {
+			l.b.onRunnerStart(runCtx, r)
+		}
+		exited := l.watchReady(runCtx, e, readier, startAt, startSpan)
+		err := l.runRunner(runCtx, r)
+		exited(err)
+		endSpan(runSpan, err)
+		if err != nil {
+			l.b.state.recordError(r.Name(), err)
+			e.started.Store(false)
+			phase := RunnerPhaseRun
+			if !e.isReady.Load() {
+				phase = RunnerPhaseStart
+			}
+			err = &RunnerError{Name: r.Name(), Phase: phase, Err: err}
+			if l.b.isOptional(r) {
+				l.optionalFailed(r, err)
+				return nil
+			}
+			if phase == RunnerPhaseStart {
+				if err := l.startFailed(err); err != nil {
+					l.b.state.setReason(ReasonRunnerError)
+					return err
+				}
+				return nil
+			}
+			l.b.state.setReason(ReasonRunnerError)
+			return err
+		}
+		return nil
+	})
+}
+
+// allReady reports whether all runners of the run set got ready, but the skipped ones and the
+// optional ones which failed.
+func (l *lifecycle) allReady() bool {
+	l.entriesMux.Lock()
+	defer l.entriesMux.Unlock()
+	for _, e := range l.entries {
+		if !e.isReady.Load() && !e.skipped.Load() && !l.b.isOptional(e.r) {
+			return false
+		}
+	}
+	return true
+}
+
+// optionalFailed logs err, failing the optional runner r, which does not bring the others down.
+func (l *lifecycle) optionalFailed(r runner.Runner, err error) {
+	l.logger.Error(fmt.Sprintf("Optional runner failed: %s", r.Name()), err)
+	l.b.warn(r.Name(), "optional runner failed", err)
+}
+
+// startFailed handles err, failing the startup of a runner. It is returned, unless the start error
+// policy is BestEffort and the startup is ongoing, in which case it is collected.
+func (l *lifecycle) startFailed(err error) error {
+	if l.b.startErrorPolicy != BestEffort {
+		return err
+	}
+	if !l.collectStartErr(err) {
+		return err
+	}
+	l.logger.Error("Runner failed to start", err)
+	return nil
+}
+
+// collectStartErr collects err, unless the startup is done. It reports whether err was collected.
+func (l *lifecycle) collectStartErr(err error) bool {
+	l.startSending.Add(1)
+	defer l.startSending.Add(-1)
+	if l.startupDone.Load() {
+		return false
+	}
+	select {
+	case l.startErrs <- err:
+	default:
+		l.startMux.Lock()
+		l.startOverflow = append(l.startOverflow, err)
+		l.startMux.Unlock()
+	}
+	return true
+}
+
+// endStartup ends the startup, returning the startup failures collected, joined.
+func (l *lifecycle) endStartup() error {
+	l.startupDone.Store(true)
+	// The failures being collected never block, wait for them to land.
+	for l.startSending.Load() > 0 {
+		runtime.Gosched()
+	}
+	var errs []error
+	for len(l.startErrs) > 0 {
+		errs = append(errs, <-l.startErrs)
+	}
+	l.startMux.Lock()
+	defer l.startMux.Unlock()
+	return joinErrors(append(errs, l.startOverflow...)...)
+}
+
+// runRunner runs r, supervised if configured, see WithSupervised.
+func (l *lifecycle) runRunner(ctx context.Context, r runner.Runner) error {
+	if cfg, ok := l.b.supervised[r.Name()]; ok {
+		return l.supervise(ctx, r, cfg)
+	}
+	return l.runWithRetry(ctx, r)
+}
+
+// runWithTimeout runs r, bounding its run with the runner timeout if configured.
+func (l *lifecycle) runWithTimeout(ctx context.Context, r runner.Runner) error {
+	d := l.b.runnerTimeouts[r.Name()]
+	if d <= 0 {
+		return r.Run(ctx)
+	}
+	runCtx, cancel := context.WithTimeout(ctx, d)
+	defer cancel()
+	err := r.Run(runCtx)
+	if ctx.Err() == nil && errors.Is(runCtx.Err(), context.DeadlineExceeded) {
+		// Cancelled by its own timeout, whatever it returned.
+		return errors.WithMessagef(ErrRunnerTimeout, "%s ran longer than %s", r.Name(), d)
+	}
+	return err
+}
+
+// signalStart signals the start of e to the startup, if it waits for it.
+func (l *lifecycle) signalStart(e *runnerEntry) {
+	if e.awaited && e.signaled.CompareAndSwap(false, true) {
+		l.waitStart.Done()
+	}
+}
+
+// awaitStartup waits for started to be closed once all runners signaled their start,
+// bounded by the startup deadline if configured.
+func (l *lifecycle) awaitStartup(ctx context.Context, started <-chan struct{}) error {
+	d := l.b.startupDeadline
+	if d <= 0 {
+		return awaitPhase(ctx, "startup", started)
+	}
+	timer := l.b.clk().NewTimer(d)
+	defer timer.Stop()
+	phaseErr := make(chan error, 1)
+	go func() {
+		phaseErr <- awaitPhase(ctx, "startup", started)
+	}()
+	select {
+	case err := <-phaseErr:
+		return err
+	case <-timer.C():
+		err := &StartupTimeoutError{Deadline: d}
+		l.entriesMux.Lock()
+		for _, e := range l.entries {
+			if e.awaited && !e.signaled.Load() {
+				err.Pending = append(err.Pending, e.r.Name())
+			}
+		}
+		l.entriesMux.Unlock()
+		return err
+	}
+}
+
+// watchReady marks e ready once its runner, running with ctx, is ready, releasing its start slot.
+// If signal is set, the readiness signals the start of the runner.
+// The start duration since startAt is observed once ready, see WithMetrics, and span is ended.
+// The returned func must be called with the result of the runner once it returns.
+func (l *lifecycle) watchReady(ctx context.Context, e *runnerEntry, signal bool, startAt time.Time,
+	span trace.Span) func(err error) {
+	mark := func(ready bool, err error) {
+		marked := e.markReady(ready)
+		if marked {
+			endSpan(span, err)
+		}
+		if marked && ready {
+			l.b.observeStart(e.r.Name(), l.b.since(startAt))
+		}
+		if marked && signal {
+			if ready {
+				l.signalReady(e)
+			}
+			l.signalStart(e)
+		}
+		l.releaseStartSlot(e)
+	}
+	if _, ok := e.r.(Readier); !ok {
+		// Ready as soon as it runs.
+		mark(true, nil)
+		return func(error) {}
+	}
+	readyCtx, cancel := context.WithCancel(ctx)
+	go func() {
+		err := awaitReady(readyCtx, e.r)
+		if err == nil {
+			mark(true, nil)
+			return
+		}
+		if readyCtx.Err() == nil {
+			// The runner is running but failed to get ready.
+			err := &RunnerError{Name: e.r.Name(), Phase: RunnerPhaseStart, Err: errors.WithMessage(err, "not ready")}
+			if l.b.isOptional(e.r) {
+				l.optionalFailed(e.r, err)
+			} else {
+				l.b.state.setReason(ReasonRunnerError)
+				l.goRun(func() error {
+					return err
+				})
+			}
+			mark(false, err)
+			return
+		}
+		mark(false, nil)
+	}()
+	return func(err error) {
+		// Marked before cancelling the watch, which would mark it without err.
+		mark(false, err)
+		cancel()
+	}
+}
+
+// signalReady signals the start of the ready runner of e, reporting it to the startup probe.
+func (l *lifecycle) signalReady(e *runnerEntry) {
+	if e.awaited && e.signaled.CompareAndSwap(false, true) {
+		l.probeStartup()
+		l.waitStart.Done()
+	}
+}
+
+// probeStartup reports a runner the startup waits for got ready to the startup probe, if any.
+// See WithStartupProbe.
+func (l *lifecycle) probeStartup() {
+	if l.b.startupProbe == nil {
+		return
+	}
+	l.probeMux.Lock()
+	defer l.probeMux.Unlock()
+	l.startupReady++
+	l.b.startupProbe(l.startupReady, l.startupTotal)
+}
+
+// releaseStartSlot releases the start slot held by e, if any.
+func (l *lifecycle) releaseStartSlot(e *runnerEntry) {
+	if l.startSem == nil {
+		return
+	}
+	e.slotOnce.Do(func() {
+		l.startSem.Release(1)
+	})
+}
+
+// goRun runs fn in the errgroup. An error of fn is the cause of the end of the run.
+func (l *lifecycle) goRun(fn func() error) {
+	l.eg.Go(func() error {
+		err := fn()
+		if err != nil {
+			l.b.state.setReason(ReasonOther)
+			l.cancelRun(err)
+		}
+		return err
+	})
+}
+
+// runAfterReady runs afterReady in the errgroup. If it fails, the bootstrap is shut down gracefully
+// and Run returns the error.
+func (l *lifecycle) runAfterReady() {
+	fn := l.b.afterReady
+	if fn == nil {
+		return
+	}
+	l.goRun(func() error {
+		err := fn(l.egCtx)
+		if err == nil {
+			return nil
+		}
+		err = errors.WithMessage(err, "afterReady err")
+		l.b.state.setReason(ReasonOnRunError)
+		l.failure.CompareAndSwap(nil, &err)
+		l.b.gs.HandleShutdown(slog.NewContext(context.Background(), l.logger), shutdown.EventFunc(func() string {
+			return err.Error()
+		}))
+		return err
+	})
+}
+
+// runOnRun runs onRun in the errgroup.
+func (l *lifecycle) runOnRun() {
+	l.goRun(func() error {
+		fn := l.b.onRun
+		if fn == nil {
+			close(l.onRunDone)
+			if l.job {
+				l.triggerShutdown()
+			}
+			return nil
+		}
+		if l.b.startupBarrier && l.readyFlipped != nil {
+			select {
+			case <-l.readyFlipped:
+			case <-l.onRunCtx.Done():
+			}
+			if !l.b.Ready() {
+				// Shutdown began before the bootstrap was ready.
+				close(l.onRunDone)
+				return nil
+			}
+		}
+		err := fn(l.onRunCtx)
+		close(l.onRunDone)
+		done := errors.Is(err, ErrDone)
+		if err != nil && !done {
+			l.b.state.setReason(ReasonOnRunError)
+			return errors.WithMessagef(err, "onRun err")
+		}
+		if (done || l.job || l.b.onRunTriggersShutdown) && l.onRunCtx.Err() == nil {
+			l.triggerShutdown()
+		}
+		return nil
+	})
+}
+
+// triggerShutdown shuts the bootstrap down gracefully as onRun completed.
+func (l *lifecycle) triggerShutdown() {
+	l.b.gs.HandleShutdown(slog.NewContext(context.Background(), l.logger), shutdown.EventFunc(func() string {
+		return string(CauseOnRunCompleted)
+	}))
+}
+
+// registerJobStop registers the shutdown callback of a run without runners, running onRun or
+// beforeRun only as a job. It ends the run once onRun is done, see OnRunLifecycle.
+func (l *lifecycle) registerJobStop() {
+	l.job = true
+	l.stopTotal.Add(1)
+	once := &sync.Once{}
+	l.b.gs.AddShutdownCallback(shutdown.CallbackFunc(func(ctx context.Context, event shutdown.Event) error {
+		if l.finished() {
+			return nil
+		}
+		once.Do(func() {
+			ctx, cancel := l.shutdownContext(ctx, event)
+			defer cancel()
+			defer l.endShutdown()
+			l.beginShutdown(ctx, event)
+		})
+		return nil
+	}))
+}
+
+// abort ends the run with err before any runner started.
+func (l *lifecycle) abort(err error) error {
+	l.cancelRun(err)
+	close(l.done)
+	return err
+}
+
+// finished reports whether Run has returned.
+// The stop callbacks it left registered in the shutdown controller are then no-ops.
+func (l *lifecycle) finished() bool {
+	select {
+	case <-l.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// wait waits for the errgroup and produces the result of Run.
+func (l *lifecycle) wait() error {
+	defer close(l.done)
+	defer l.cancelTriggers()
+	defer l.cancelOnRun()
+	err := l.eg.Wait()
+	l.cancelRun(nil)
+	l.b.state.setStopCause(context.Cause(l.egCtx))
+	if failure := l.failure.Load(); failure != nil && (err == nil || errors.Is(err, context.Canceled)) {
+		// The failure shut down gracefully, which may have ended the errgroup first.
+		err = *failure
+	}
+	if err != nil && !errors.Is(err, context.Canceled) {
+		bErr := &BootstrapError{Err: err}
+		l.entriesMux.Lock()
+		for _, e := range l.entries {
+			if e.started.Load() {
+				bErr.Started = append(bErr.Started, e.r.Name())
+			}
+		}
+		l.entriesMux.Unlock()
+		err = bErr
+	} else {
+		err = nil
+	}
+	return joinErrors(err, l.stopErrs.err(), l.shutdownTimeoutErr())
+}
+
+// stopTimedOut records the runner named name was still stopping when the shutdown timed out.
+func (l *lifecycle) stopTimedOut(name string) {
+	l.stopMux.Lock()
+	defer l.stopMux.Unlock()
+	l.timedOut = append(l.timedOut, name)
+}
+
+// shutdownTimeoutErr returns an error wrapping ErrShutdownTimeout if runners were still stopping
+// when the shutdown timed out.
+func (l *lifecycle) shutdownTimeoutErr() error {
+	l.stopMux.Lock()
+	defer l.stopMux.Unlock()
+	if len(l.timedOut) == 0 {
+		return nil
+	}
+	return errors.WithMessagef(ErrShutdownTimeout, "runners stopping: %s", strings.Join(l.timedOut, ", "))
+}
@@ -0,0 +1,220 @@
+package bootstrap
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/exp/slog"
+
+	"github.com/yimi-go/runner"
+	"github.com/yimi-go/shutdown"
+)
+
+func Test_lifecycle_shutdown(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	b := New(
+		WithRunners(NewMockRunner(ctrl), NewMockRunner(ctrl)),
+		WithOnRunLifecycle(OnRunDrain),
+	).(bootstrap)
+	b.state.store(phaseRunning)
+	l := newLifecycle(context.Background(), b, slog.Default(), nil, time.Now())
+	l.stopTotal.Store(2)
+	l.beginShutdown(context.Background(), shutdown.EventFunc(func() string {
+		return "test"
+	}))
+	assert.Equal(t, phaseStopping, b.state.load())
+	assert.Nil(t, l.onRunCtx.Err())
+	l.endShutdown()
+	assert.Nil(t, l.onRunCtx.Err())
+	l.endShutdown()
+	assert.NotNil(t, l.onRunCtx.Err())
+	assert.NotNil(t, l.triggerCtx.Err())
+}
+
+func Test_lifecycle_wait(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	r := NewMockRunner(ctrl)
+	r.EXPECT().Name().Return("testRunner").AnyTimes()
+	r.EXPECT().Run(gomock.Any()).Return(context.Canceled)
+	b := New(WithRunners([]runner.Runner{r}...)).(bootstrap)
+	l := newLifecycle(context.Background(), b, slog.Default(), nil, time.Now())
+	e := l.addEntry(r)
+	l.startRunner(e, true)
+	l.runOnRun()
+	assert.Nil(t, l.wait())
+	assert.False(t, e.started.Load())
+}
+
+func Test_lifecycle_registerStop(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	r := NewMockRunner(ctrl)
+	r.EXPECT().Name().Return("testRunner").AnyTimes()
+	r.EXPECT().Stop(gomock.Any()).Return(nil).Times(1)
+	gs := shutdown.NewGraceful()
+	b := New(WithRunners(r), WithShutdown(gs)).(bootstrap)
+	l := newLifecycle(context.Background(), b, slog.Default(), nil, time.Now())
+	l.registerStop(r)
+	l.registerStop(r)
+	event := shutdown.EventFunc(func() string {
+		return "test"
+	})
+	gs.HandleShutdown(context.Background(), event)
+	gs.HandleShutdown(context.Background(), event)
+	assert.Equal(t, int32(1), l.stoppedCount.Load())
+}
+
+func Test_lifecycle_groupConcurrency(t *testing.T) {
+	b := New(WithGroupConcurrency(1)).(bootstrap)
+	l := newLifecycle(context.Background(), b, slog.Default(), nil, time.Now())
+	assert.True(t, l.runSem.TryAcquire(1))
+	assert.False(t, l.runSem.TryAcquire(1))
+	// The group itself is not limited.
+	release := make(chan struct{})
+	l.eg.Go(func() error {
+		<-release
+		return nil
+	})
+	assert.True(t, l.eg.TryGo(func() error {
+		return nil
+	}))
+	close(release)
+	assert.Nil(t, l.eg.Wait())
+}
+
+func TestBootstrap_Run_shutdownProbe(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var rs []runner.Runner
+	for _, name := range []string{"a", "b", "c"} {
+		r := NewMockRunner(ctrl)
+		r.EXPECT().Name().Return(name).AnyTimes()
+		r.EXPECT().Run(gomock.Any()).DoAndReturn(func(ctx context.Context) error {
+			<-ctx.Done()
+			return nil
+		})
+		r.EXPECT().Stop(gomock.Any()).Return(nil)
+		rs = append(rs, r)
+	}
+	var progress [][2]int
+	b := New(WithRunners(rs...), WithShutdownProbe(func(stopped, total int) {
+		progress = append(progress, [2]int{stopped, total})
+	}), WithOnRun(func(ctx context.Context) error {
+		cancel()
+		return nil
+	}))
+	assert.Nil(t, b.Run(ctx))
+	assert.Equal(t, [][2]int{{1, 3}, {2, 3}, {3, 3}}, progress)
+}
+
+// earlyController fires a shutdown as soon as it waits, recording the callbacks registered then.
+type earlyController struct {
+	shutdown.Controller
+	callbacks atomic.Int32
+	atWait    atomic.Int32
+}
+
+func (c *earlyController) AddShutdownCallback(callback shutdown.Callback) {
+	// Slow registration, giving an early Wait the chance to miss callbacks.
+	time.Sleep(time.Millisecond)
+	c.Controller.AddShutdownCallback(callback)
+	c.callbacks.Add(1)
+}
+
+func (c *earlyController) Wait(ctx context.Context) error {
+	c.atWait.Store(c.callbacks.Load())
+	c.HandleShutdown(ctx, shutdown.EventFunc(func() string {
+		return "received signal: terminated"
+	}))
+	<-ctx.Done()
+	return nil
+}
+
+func TestBootstrap_Run_immediateTrigger(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	var stopped atomic.Int32
+	var rs []runner.Runner
+	for _, name := range []string{"a", "b", "c", "d", "e"} {
+		r := NewMockRunner(ctrl)
+		r.EXPECT().Name().Return(name).AnyTimes()
+		r.EXPECT().Run(gomock.Any()).DoAndReturn(func(ctx context.Context) error {
+			<-ctx.Done()
+			return nil
+		}).AnyTimes()
+		r.EXPECT().Stop(gomock.Any()).DoAndReturn(func(ctx context.Context) error {
+			stopped.Add(1)
+			return nil
+		})
+		rs = append(rs, r)
+	}
+	gs := &earlyController{Controller: shutdown.NewGraceful()}
+	done := make(chan error)
+	go func() {
+		done <- New(WithRunners(rs...), WithShutdown(gs)).Run(context.Background())
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("timeout")
+	}
+	assert.Equal(t, int32(5), gs.atWait.Load())
+	assert.Equal(t, int32(5), stopped.Load())
+}
+
+func TestBootstrap_Run_inFlightGrace(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var stoppedAt, doneAt atomic.Pointer[time.Time]
+	r := NewMockRunner(ctrl)
+	r.EXPECT().Name().Return("testRunner").AnyTimes()
+	r.EXPECT().Run(gomock.Any()).DoAndReturn(func(ctx context.Context) error {
+		<-ctx.Done()
+		now := time.Now()
+		doneAt.Store(&now)
+		return nil
+	})
+	r.EXPECT().Stop(gomock.Any()).DoAndReturn(func(ctx context.Context) error {
+		now := time.Now()
+		stoppedAt.Store(&now)
+		return nil
+	})
+	b := New(WithRunners(r), WithInFlightGrace(time.Millisecond*100))
+	done := make(chan error)
+	go func() {
+		done <- b.Run(ctx)
+	}()
+	assert.Eventually(t, b.Ready, time.Second, time.Millisecond)
+	assert.Nil(t, b.Context().Err())
+	cancelAt := time.Now()
+	cancel()
+	assert.Nil(t, <-done)
+	if assert.NotNil(t, stoppedAt.Load()) && assert.NotNil(t, doneAt.Load()) {
+		assert.True(t, stoppedAt.Load().Before(*doneAt.Load()))
+		assert.GreaterOrEqual(t, doneAt.Load().Sub(cancelAt), time.Millisecond*100)
+	}
+	// The contexts of the runners keep the deadline of the run context.
+	deadline := time.Now().Add(time.Hour)
+	ctx, cancel = context.WithDeadline(context.Background(), deadline)
+	defer cancel()
+	r.EXPECT().Run(gomock.Any()).DoAndReturn(func(runCtx context.Context) error {
+		d, ok := runCtx.Deadline()
+		assert.True(t, ok)
+		assert.Equal(t, deadline, d)
+		cancel()
+		<-runCtx.Done()
+		return nil
+	})
+	r.EXPECT().Stop(gomock.Any()).Return(nil)
+	assert.Nil(t, b.Run(ctx))
+}
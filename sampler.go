@@ -0,0 +1,16 @@
+package bootstrap
+
+// The lifecycle log events of runners, passed to the log sampler. See WithLogSampler.
+const (
+	// LogEventStart is the "Starting runner" log.
+	LogEventStart = "start"
+	// LogEventStop is the "Stopping runner" log.
+	LogEventStop = "stop"
+	// LogEventStopped is the "Runner stopped" log.
+	LogEventStopped = "stopped"
+)
+
+// sampled reports whether the lifecycle log event of the runner named name is to be emitted.
+func (b bootstrap) sampled(name, event string) bool {
+	return b.logSampler == nil || b.logSampler(name, event)
+}
@@ -0,0 +1,114 @@
+package bootstrap
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	"golang.org/x/exp/slog"
+
+	"github.com/yimi-go/shutdown"
+)
+
+// Indirections of process-wide effects, replaced in tests.
+var (
+	exitFunc     = os.Exit
+	signalNotify = signal.Notify
+	signalStop   = signal.Stop
+)
+
+// watchForceExit exits the process with the configured code once SIGINT or SIGTERM is received
+// before the lifecycle is done. It is started when shutdown begins, so the signal which began the
+// shutdown is not observed.
+func (l *lifecycle) watchForceExit() {
+	ch := make(chan os.Signal, 1)
+	signalNotify(ch, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		defer signalStop(ch)
+		select {
+		case sig := <-ch:
+			if l.logger.Enabled(slog.ErrorLevel) {
+				l.logger.Log(slog.ErrorLevel,
+					fmt.Sprintf("Received %s during shutdown, force exiting with code %d", sig, l.b.forceExitCode))
+			}
+			exitFunc(l.b.forceExitCode)
+		case <-l.done:
+		}
+	}()
+}
+
+// watchReload runs the reload func each time SIGHUP is received, until the runners are to stop.
+func (l *lifecycle) watchReload() {
+	ch := make(chan os.Signal, 1)
+	signalNotify(ch, syscall.SIGHUP)
+	go func() {
+		defer signalStop(ch)
+		for {
+			select {
+			case <-ch:
+			case <-l.egCtx.Done():
+				return
+			}
+			if err := l.b.reload(l.egCtx); err != nil {
+				l.logger.Error("Reload failed", err)
+				continue
+			}
+			if l.logger.Enabled(slog.InfoLevel) {
+				l.logger.Info("Reloaded.")
+			}
+		}
+	}()
+}
+
+// signalsByName are the signals recognized in the reasons of shutdown events, by name.
+var signalsByName = map[string]os.Signal{
+	os.Interrupt.String():    os.Interrupt,
+	syscall.SIGTERM.String(): syscall.SIGTERM,
+	syscall.SIGHUP.String():  syscall.SIGHUP,
+	syscall.SIGQUIT.String(): syscall.SIGQUIT,
+}
+
+// signalOf returns the signal a shutdown event with reason was triggered by, if any.
+func signalOf(reason string) (os.Signal, bool) {
+	name, ok := strings.CutPrefix(reason, "received signal: ")
+	if !ok {
+		return nil, false
+	}
+	sig, ok := signalsByName[name]
+	return sig, ok
+}
+
+// signalTrigger wraps a trigger, observing the signals it triggers shutdowns with.
+type signalTrigger struct {
+	shutdown.Trigger
+	onSignal func(sig os.Signal)
+}
+
+func (t signalTrigger) Wait(ctx context.Context, c shutdown.Controller) error {
+	return t.Trigger.Wait(ctx, signalController{Controller: c, onSignal: t.onSignal})
+}
+
+// signalController passes the signal of a shutdown event to onSignal before handling it.
+type signalController struct {
+	shutdown.Controller
+	onSignal func(sig os.Signal)
+}
+
+func (c signalController) HandleShutdown(ctx context.Context, event shutdown.Event) {
+	if sig, ok := signalOf(event.Reason()); ok {
+		c.onSignal(sig)
+	}
+	c.Controller.HandleShutdown(ctx, event)
+}
+
+// observeSignals wraps the triggers to pass the signals they trigger shutdowns with to onSignal.
+func observeSignals(triggers []shutdown.Trigger, onSignal func(sig os.Signal)) []shutdown.Trigger {
+	wrapped := make([]shutdown.Trigger, 0, len(triggers))
+	for _, t := range triggers {
+		wrapped = append(wrapped, signalTrigger{Trigger: t, onSignal: onSignal})
+	}
+	return wrapped
+}
@@ -0,0 +1,192 @@
+package bootstrap
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/yimi-go/shutdown"
+)
+
+// fakeSignals replaces the process-wide signal subscription with channels the test can send to.
+type fakeSignals struct {
+	mux  sync.Mutex
+	subs map[chan<- os.Signal][]os.Signal
+}
+
+func installFakeSignals(t *testing.T) *fakeSignals {
+	fs := &fakeSignals{subs: map[chan<- os.Signal][]os.Signal{}}
+	notify, stop := signalNotify, signalStop
+	signalNotify = func(c chan<- os.Signal, sig ...os.Signal) {
+		fs.mux.Lock()
+		defer fs.mux.Unlock()
+		fs.subs[c] = append(fs.subs[c], sig...)
+	}
+	signalStop = func(c chan<- os.Signal) {
+		fs.mux.Lock()
+		defer fs.mux.Unlock()
+		delete(fs.subs, c)
+	}
+	t.Cleanup(func() {
+		signalNotify, signalStop = notify, stop
+	})
+	return fs
+}
+
+// send delivers sig to the subscribers of it. It reports the number of subscribers.
+func (fs *fakeSignals) send(sig os.Signal) int {
+	fs.mux.Lock()
+	defer fs.mux.Unlock()
+	n := 0
+	for c, sigs := range fs.subs {
+		for _, s := range sigs {
+			if s == sig {
+				select {
+				case c <- sig:
+				default:
+				}
+				n++
+				break
+			}
+		}
+	}
+	return n
+}
+
+func TestBootstrap_Run_forceExitOnSecondSignal(t *testing.T) {
+	fs := installFakeSignals(t)
+	exited := make(chan int, 1)
+	exit := exitFunc
+	exitFunc = func(code int) {
+		exited <- code
+	}
+	defer func() {
+		exitFunc = exit
+	}()
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	r := NewMockRunner(ctrl)
+	r.EXPECT().Name().Return("testRunner").AnyTimes()
+	r.EXPECT().Run(gomock.Any()).DoAndReturn(func(ctx context.Context) error {
+		<-ctx.Done()
+		return nil
+	})
+	r.EXPECT().Stop(gomock.Any()).DoAndReturn(func(ctx context.Context) error {
+		assert.Equal(t, 1, fs.send(syscall.SIGTERM))
+		select {
+		case code := <-exited:
+			assert.Equal(t, 130, code)
+		case <-time.After(time.Second):
+			t.Error("not exited")
+		}
+		return nil
+	})
+	b := New(WithRunners(r), WithForceExitOnSecondSignal(130))
+	go func() {
+		<-time.After(time.Millisecond * 10)
+		assert.Equal(t, 0, fs.send(syscall.SIGTERM))
+		cancel()
+	}()
+	assert.Nil(t, b.Run(ctx))
+	assert.Eventually(t, func() bool {
+		return fs.send(syscall.SIGTERM) == 0
+	}, time.Second, time.Millisecond)
+}
+
+func Test_signalOf(t *testing.T) {
+	sig, ok := signalOf("received signal: terminated")
+	assert.True(t, ok)
+	assert.Equal(t, syscall.SIGTERM, sig)
+	sig, ok = signalOf("received signal: interrupt")
+	assert.True(t, ok)
+	assert.Equal(t, os.Interrupt, sig)
+	_, ok = signalOf("received signal: unknown")
+	assert.False(t, ok)
+	_, ok = signalOf("context canceled")
+	assert.False(t, ok)
+}
+
+func TestBootstrap_Run_onSignal(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	r := NewMockRunner(ctrl)
+	r.EXPECT().Name().Return("testRunner").AnyTimes()
+	stopped := make(chan struct{})
+	r.EXPECT().Run(gomock.Any()).DoAndReturn(func(ctx context.Context) error {
+		<-stopped
+		return nil
+	})
+	var signals []os.Signal
+	r.EXPECT().Stop(gomock.Any()).DoAndReturn(func(ctx context.Context) error {
+		// The signal is observed before the shutdown.
+		assert.Equal(t, []os.Signal{syscall.SIGTERM}, signals)
+		close(stopped)
+		return nil
+	})
+	// tr is a signal source, triggering the shutdown as the POSIX signal trigger does.
+	tr := NewMockTrigger(ctrl)
+	tr.EXPECT().Wait(gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, c shutdown.Controller) error {
+		c.HandleShutdown(ctx, shutdown.EventFunc(func() string {
+			return fmt.Sprintf("received signal: %s", syscall.SIGTERM)
+		}))
+		return nil
+	})
+	b := New(WithRunners(r), WithTriggers(tr), WithOnSignal(func(sig os.Signal) {
+		signals = append(signals, sig)
+	}))
+	assert.Nil(t, b.Run(context.Background()))
+	assert.Equal(t, []os.Signal{syscall.SIGTERM}, signals)
+}
+
+func TestBootstrap_Run_reload(t *testing.T) {
+	fs := installFakeSignals(t)
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	r := NewMockRunner(ctrl)
+	r.EXPECT().Name().Return("testRunner").AnyTimes()
+	r.EXPECT().Run(gomock.Any()).DoAndReturn(func(ctx context.Context) error {
+		<-ctx.Done()
+		return nil
+	})
+	r.EXPECT().Stop(gomock.Any()).Return(nil)
+	var reloads atomic.Int32
+	b := New(WithRunners(r), WithReload(func(ctx context.Context) error {
+		if reloads.Add(1) == 2 {
+			return errors.New("test")
+		}
+		return nil
+	}))
+	done := make(chan error)
+	go func() {
+		done <- b.Run(ctx)
+	}()
+	// Sends once subscribed.
+	assert.Eventually(t, func() bool {
+		return fs.send(syscall.SIGHUP) == 1
+	}, time.Second, time.Millisecond)
+	assert.Eventually(t, func() bool {
+		return reloads.Load() == 1
+	}, time.Second, time.Millisecond)
+	assert.Equal(t, 1, fs.send(syscall.SIGHUP))
+	assert.Eventually(t, func() bool {
+		return reloads.Load() == 2
+	}, time.Second, time.Millisecond)
+	// A failed reload does not stop the runners either.
+	time.Sleep(time.Millisecond * 10)
+	assert.True(t, b.Ready())
+	cancel()
+	assert.Nil(t, <-done)
+}
@@ -0,0 +1,25 @@
+package bootstrap
+
+// StartErrorPolicy defines how the runners failing to start are handled.
+// A runner fails to start if it returns an error before it is ready, see Readier,
+// or if it cannot start because of its dependencies.
+type StartErrorPolicy int
+
+const (
+	// FailFast shuts the bootstrap down on the first runner failing to start. It is the default.
+	FailFast StartErrorPolicy = iota
+	// BestEffort starts all the runners, collecting the start failures. If any, the runners which
+	// started are shut down gracefully once the startup completes, and Run returns the failures.
+	BestEffort
+)
+
+func (p StartErrorPolicy) String() string {
+	switch p {
+	case FailFast:
+		return "fail fast"
+	case BestEffort:
+		return "best effort"
+	default:
+		return "unknown"
+	}
+}
@@ -0,0 +1,178 @@
+package bootstrap
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/exp/slog"
+
+	"github.com/yimi-go/runner"
+)
+
+func TestStartErrorPolicy_String(t *testing.T) {
+	assert.Equal(t, "fail fast", FailFast.String())
+	assert.Equal(t, "best effort", BestEffort.String())
+	assert.Equal(t, "unknown", StartErrorPolicy(-1).String())
+}
+
+func TestBootstrap_Run_startErrorPolicy(t *testing.T) {
+	// run runs two runners failing before ready, and a healthy one, with policy.
+	// It returns the errors of the failing runners, whether the healthy one was stopped and
+	// the result of Run.
+	run := func(t *testing.T, policy StartErrorPolicy) (errs []error, stopped bool, err error) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		var opts []Option
+		for _, name := range []string{"failing1", "failing2"} {
+			runErr := errors.New(name)
+			errs = append(errs, runErr)
+			r := readierRunner{MockRunner: NewMockRunner(ctrl), waitReady: func(ctx context.Context) error {
+				<-ctx.Done()
+				return ctx.Err()
+			}}
+			r.EXPECT().Name().Return(name).AnyTimes()
+			r.EXPECT().Run(gomock.Any()).Return(runErr).MaxTimes(1)
+			r.EXPECT().Stop(gomock.Any()).Return(nil).AnyTimes()
+			opts = append(opts, WithRunners(r))
+		}
+		healthy := NewMockRunner(ctrl)
+		healthy.EXPECT().Name().Return("healthy").AnyTimes()
+		healthy.EXPECT().Run(gomock.Any()).DoAndReturn(func(ctx context.Context) error {
+			<-ctx.Done()
+			return nil
+		}).MaxTimes(1)
+		healthy.EXPECT().Stop(gomock.Any()).DoAndReturn(func(ctx context.Context) error {
+			stopped = true
+			return nil
+		}).AnyTimes()
+		opts = append(opts, WithRunners(healthy), WithStartErrorPolicy(policy))
+		err = New(opts...).Run(context.Background())
+		return
+	}
+	t.Run("fail_fast", func(t *testing.T) {
+		errs, _, err := run(t, FailFast)
+		var runnerErr *RunnerError
+		if assert.ErrorAs(t, err, &runnerErr) {
+			assert.Equal(t, RunnerPhaseStart, runnerErr.Phase)
+		}
+		// The first failure is returned only.
+		assert.True(t, errors.Is(err, errs[0]) != errors.Is(err, errs[1]))
+	})
+	t.Run("best_effort", func(t *testing.T) {
+		errs, stopped, err := run(t, BestEffort)
+		assert.ErrorIs(t, err, errs[0])
+		assert.ErrorIs(t, err, errs[1])
+		assert.True(t, stopped)
+		var bErr *BootstrapError
+		if assert.ErrorAs(t, err, &bErr) {
+			assert.Equal(t, []string{"healthy"}, bErr.Started)
+		}
+	})
+}
+
+func TestBootstrap_Run_startErrorPolicy_fanOut(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	var errs []error
+	var rs []runner.Runner
+	for i := 0; i < 100; i++ {
+		name := fmt.Sprintf("failing%d", i)
+		runErr := errors.New(name)
+		errs = append(errs, runErr)
+		r := readierRunner{MockRunner: NewMockRunner(ctrl), waitReady: func(ctx context.Context) error {
+			<-ctx.Done()
+			return ctx.Err()
+		}}
+		r.EXPECT().Name().Return(name).AnyTimes()
+		r.EXPECT().Run(gomock.Any()).Return(runErr)
+		r.EXPECT().Stop(gomock.Any()).Return(nil).AnyTimes()
+		rs = append(rs, r)
+	}
+	err := New(WithRunners(rs...), WithStartErrorPolicy(BestEffort)).Run(context.Background())
+	for _, runErr := range errs {
+		assert.ErrorIs(t, err, runErr)
+	}
+}
+
+func Test_lifecycle_startFailed(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard))
+	// Sized for one runner, the failures of the runners added meanwhile overflow.
+	l := &lifecycle{b: bootstrap{startErrorPolicy: BestEffort}, logger: logger, startErrs: make(chan error, 1)}
+	errs := []error{errors.New("a"), errors.New("b"), errors.New("c")}
+	for _, err := range errs {
+		assert.Nil(t, l.startFailed(err))
+	}
+	err := l.endStartup()
+	for _, e := range errs {
+		assert.ErrorIs(t, err, e)
+	}
+	// Returned once the startup is done.
+	late := errors.New("late")
+	assert.Same(t, late, l.startFailed(late))
+}
+
+// mutexStartErrs is the mutex guarded collection of startup failures, benchmarked against
+// the channel of the lifecycle.
+type mutexStartErrs struct {
+	mux    sync.Mutex
+	logger *slog.Logger
+	errs   []error
+	done   bool
+}
+
+func (c *mutexStartErrs) collect(err error) error {
+	c.mux.Lock()
+	if c.done {
+		c.mux.Unlock()
+		return err
+	}
+	c.errs = append(c.errs, err)
+	c.mux.Unlock()
+	c.logger.Error("Runner failed to start", err)
+	return nil
+}
+
+func (c *mutexStartErrs) end() error {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	c.done = true
+	return joinErrors(c.errs...)
+}
+
+func BenchmarkStartErrs(b *testing.B) {
+	const fanOut = 1000
+	err := errors.New("test")
+	logger := slog.New(slog.NewTextHandler(io.Discard))
+	collectAll := func(collect func(err error) error) {
+		wg := sync.WaitGroup{}
+		wg.Add(fanOut)
+		for i := 0; i < fanOut; i++ {
+			go func() {
+				defer wg.Done()
+				_ = collect(err)
+			}()
+		}
+		wg.Wait()
+	}
+	b.Run("channel", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			l := &lifecycle{b: bootstrap{startErrorPolicy: BestEffort}, logger: logger,
+				startErrs: make(chan error, fanOut)}
+			collectAll(l.startFailed)
+			_ = l.endStartup()
+		}
+	})
+	b.Run("mutex", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			c := &mutexStartErrs{logger: logger}
+			collectAll(c.collect)
+			_ = c.end()
+		}
+	})
+}
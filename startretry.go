@@ -0,0 +1,48 @@
+package bootstrap
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/yimi-go/runner"
+	"golang.org/x/exp/slog"
+)
+
+// startRetry is the retry policy of a runner failing with retryable errors, see WithStartRetry.
+type startRetry struct {
+	isRetryable func(err error) bool
+	attempts    int
+	backoff     time.Duration
+}
+
+// runWithRetry runs r, retrying it as its start retry policy tells while it fails with
+// retryable errors. Other errors are returned right away.
+func (l *lifecycle) runWithRetry(ctx context.Context, r runner.Runner) error {
+	retry, ok := l.b.startRetries[r.Name()]
+	if !ok {
+		return l.runWithTimeout(ctx, r)
+	}
+	for attempt := 1; ; attempt++ {
+		err := l.runWithTimeout(ctx, r)
+		if err == nil || !retry.isRetryable(err) || l.stopped(ctx) {
+			return err
+		}
+		if attempt >= retry.attempts {
+			return errors.WithMessagef(err, "run %s failed after %d attempts", r.Name(), attempt)
+		}
+		if l.logger.Enabled(slog.WarnLevel) {
+			l.logger.Warn(fmt.Sprintf("Runner %s failed with a retryable error, retrying in %s", r.Name(), retry.backoff),
+				slog.Int("attempt", attempt), slog.Any(slog.ErrorKey, err))
+		}
+		select {
+		case <-l.b.clk().After(retry.backoff):
+		case <-ctx.Done():
+			return nil
+		}
+		if l.stopped(ctx) {
+			return nil
+		}
+	}
+}
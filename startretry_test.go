@@ -0,0 +1,71 @@
+package bootstrap
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBootstrap_Run_startRetry(t *testing.T) {
+	errNotReady := errors.New("dependency not ready")
+	isNotReady := func(err error) bool {
+		return errors.Is(err, errNotReady)
+	}
+	// newRunner returns a runner failing with errs on its first runs, then running until its
+	// context is done, and the number of its runs.
+	newRunner := func(ctrl *gomock.Controller, errs ...error) (*MockRunner, *atomic.Int32) {
+		runs := &atomic.Int32{}
+		r := NewMockRunner(ctrl)
+		r.EXPECT().Name().Return("db").AnyTimes()
+		r.EXPECT().Run(gomock.Any()).DoAndReturn(func(ctx context.Context) error {
+			n := int(runs.Add(1))
+			if n <= len(errs) {
+				return errs[n-1]
+			}
+			<-ctx.Done()
+			return nil
+		}).AnyTimes()
+		r.EXPECT().Stop(gomock.Any()).Return(nil).AnyTimes()
+		return r, runs
+	}
+	t.Run("retryable", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		r, runs := newRunner(ctrl, errNotReady, errNotReady)
+		b := New(WithRunners(r), WithStartRetry("db", isNotReady, 3, time.Millisecond))
+		done := make(chan error)
+		go func() {
+			done <- b.Run(ctx)
+		}()
+		assert.Eventually(t, func() bool {
+			return runs.Load() == 3
+		}, time.Second, time.Millisecond)
+		cancel()
+		assert.Nil(t, <-done)
+	})
+	t.Run("exhausted", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		r, runs := newRunner(ctrl, errNotReady, errNotReady, errNotReady)
+		err := New(WithRunners(r), WithStartRetry("db", isNotReady, 2, time.Millisecond)).Run(context.Background())
+		assert.ErrorIs(t, err, errNotReady)
+		assert.Contains(t, err.Error(), "run db failed after 2 attempts")
+		assert.EqualValues(t, 2, runs.Load())
+	})
+	t.Run("fatal", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		fatalErr := errors.New("bad credentials")
+		r, runs := newRunner(ctrl, fatalErr)
+		err := New(WithRunners(r), WithStartRetry("db", isNotReady, 3, time.Millisecond)).Run(context.Background())
+		assert.ErrorIs(t, err, fatalErr)
+		assert.EqualValues(t, 1, runs.Load())
+	})
+}
@@ -0,0 +1,198 @@
+package bootstrap
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/yimi-go/runner"
+	"github.com/yimi-go/shutdown"
+)
+
+// phase is the lifecycle phase of a bootstrap.
+type phase int32
+
+const (
+	phaseIdle phase = iota
+	phaseStarting
+	phaseRunning
+	phaseStopping
+	phaseStopped
+)
+
+// runState holds the runtime state of a bootstrap, shared by all copies of it.
+type runState struct {
+	phase  atomic.Int32
+	values sync.Map
+	// shutdownTimeout is the timeout set by SetShutdownTimeout, in nanoseconds.
+	shutdownTimeout atomic.Int64
+	// startedAt is when the last Run started, or nil.
+	startedAt atomic.Pointer[time.Time]
+	// stoppedAt is when the last Run stopped, or nil while it runs.
+	stoppedAt atomic.Pointer[time.Time]
+
+	mux sync.Mutex
+	// dynamic holds the runners added by AddRunner.
+	dynamic []runner.Runner
+	// current is the lifecycle of the ongoing Run, if any.
+	current *lifecycle
+	// warnings are the warnings recorded during the last Run.
+	warnings []Warning
+	// lastErrors maps runner names to their last Run or Stop error during the last Run.
+	lastErrors map[string]error
+	// stopCause is why the last Run stopped.
+	stopCause error
+	// event is the first shutdown event of the last Run.
+	event shutdown.Event
+	// reason is why the last Run stopped, as first detected.
+	reason atomic.Int32
+	// started receives the result of the Run launched by Start, until Stop takes it.
+	started chan error
+	// stopRequested records a Stop called during the startup, before the stop callbacks are armed.
+	stopRequested bool
+	// stopArmed is set once the stop callbacks of the ongoing Run are registered.
+	stopArmed bool
+	// runs counts the Runs begun.
+	runs atomic.Int64
+}
+
+func (s *runState) load() phase {
+	if s == nil {
+		return phaseIdle
+	}
+	return phase(s.phase.Load())
+}
+
+func (s *runState) store(p phase) {
+	s.phase.Store(int32(p))
+}
+
+// beginRun moves the state to starting, forgetting the Stop requests of a previous Run.
+func (s *runState) beginRun() {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	s.stopRequested = false
+	s.stopArmed = false
+	s.stoppedAt.Store(nil)
+	s.runs.Add(1)
+	s.store(phaseStarting)
+}
+
+// requestStop records a Stop called during the startup, before the stop callbacks are armed.
+// It reports false if they are, the shutdown is then to be handled by the controller.
+func (s *runState) requestStop() bool {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	if s.load() != phaseStarting || s.stopArmed {
+		return false
+	}
+	s.stopRequested = true
+	return true
+}
+
+// armStop records that the stop callbacks of the ongoing Run are registered.
+// It reports false if Stop has been called before.
+func (s *runState) armStop() bool {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	if s.stopRequested {
+		return false
+	}
+	s.stopArmed = true
+	return true
+}
+
+// markRunning moves the state from starting to running.
+// It reports false if the state has left the starting phase, e.g. shutdown has begun.
+func (s *runState) markRunning() bool {
+	return s.phase.CompareAndSwap(int32(phaseStarting), int32(phaseRunning))
+}
+
+// markStopping moves the state to stopping. It reports whether this call made the move.
+func (s *runState) markStopping() bool {
+	for {
+		p := s.phase.Load()
+		if p >= int32(phaseStopping) {
+			return false
+		}
+		if s.phase.CompareAndSwap(p, int32(phaseStopping)) {
+			return true
+		}
+	}
+}
+
+// Ready reports whether the bootstrap has started and is not shutting down.
+func (b bootstrap) Ready() bool {
+	return b.state.load() == phaseRunning
+}
+
+// SetValue associates val with key on this bootstrap, e.g. to share state between plugins.
+func (b bootstrap) SetValue(key, val any) {
+	if b.state == nil {
+		return
+	}
+	b.state.values.Store(key, val)
+}
+
+// Value returns the value associated with key on this bootstrap, or nil.
+func (b bootstrap) Value(key any) any {
+	if b.state == nil {
+		return nil
+	}
+	val, _ := b.state.values.Load(key)
+	return val
+}
+
+// StartedAt returns when the last Run started, or the zero time if it has not started yet.
+func (b bootstrap) StartedAt() time.Time {
+	if b.state == nil {
+		return time.Time{}
+	}
+	if at := b.state.startedAt.Load(); at != nil {
+		return *at
+	}
+	return time.Time{}
+}
+
+// Uptime returns how long the bootstrap has been up since its last Run started, or 0.
+// Once that Run stopped, it is how long the bootstrap was up.
+func (b bootstrap) Uptime() time.Duration {
+	at := b.StartedAt()
+	if at.IsZero() {
+		return 0
+	}
+	if stopped := b.state.stoppedAt.Load(); stopped != nil && !stopped.Before(at) {
+		return stopped.Sub(at)
+	}
+	return b.since(at)
+}
+
+// takeStarted takes the result channel of the Run launched by Start, if any.
+func (s *runState) takeStarted() chan error {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	started := s.started
+	s.started = nil
+	return started
+}
+
+// LastError returns the last error returned by the Run or Stop of the runner named name,
+// during the last Run of this bootstrap, or nil.
+func (b bootstrap) LastError(name string) error {
+	if b.state == nil {
+		return nil
+	}
+	b.state.mux.Lock()
+	defer b.state.mux.Unlock()
+	return b.state.lastErrors[name]
+}
+
+// recordError records err as the last error of the runner named name.
+func (s *runState) recordError(name string, err error) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	if s.lastErrors == nil {
+		s.lastErrors = map[string]error{}
+	}
+	s.lastErrors[name] = err
+}
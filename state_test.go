@@ -0,0 +1,312 @@
+package bootstrap
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_runState(t *testing.T) {
+	var nilState *runState
+	assert.Equal(t, phaseIdle, nilState.load())
+	s := &runState{}
+	assert.False(t, s.markRunning())
+	s.store(phaseStarting)
+	assert.True(t, s.markStopping())
+	assert.False(t, s.markStopping())
+	assert.False(t, s.markRunning())
+	assert.Equal(t, phaseStopping, s.load())
+}
+
+func TestBootstrap_Ready(t *testing.T) {
+	t.Run("ready", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		r := NewMockRunner(ctrl)
+		r.EXPECT().Name().Return("testRunner").AnyTimes()
+		r.EXPECT().Run(gomock.Any()).DoAndReturn(func(ctx context.Context) error {
+			<-ctx.Done()
+			return nil
+		})
+		r.EXPECT().Stop(gomock.Any()).Return(nil)
+		b := New(WithRunners(r))
+		assert.False(t, b.Ready())
+		done := make(chan error)
+		go func() {
+			done <- b.Run(ctx)
+		}()
+		assert.Eventually(t, b.Ready, time.Second, time.Millisecond)
+		cancel()
+		assert.Nil(t, <-done)
+		assert.False(t, b.Ready())
+	})
+	t.Run("delay", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		r := NewMockRunner(ctrl)
+		r.EXPECT().Name().Return("testRunner").AnyTimes()
+		running := make(chan struct{})
+		r.EXPECT().Run(gomock.Any()).DoAndReturn(func(ctx context.Context) error {
+			close(running)
+			<-ctx.Done()
+			return nil
+		})
+		r.EXPECT().Stop(gomock.Any()).Return(nil)
+		b := New(WithRunners(r), WithReadyDelay(time.Millisecond*50))
+		start := time.Now()
+		done := make(chan error)
+		go func() {
+			done <- b.Run(ctx)
+		}()
+		<-running
+		assert.False(t, b.Ready())
+		assert.Eventually(t, b.Ready, time.Second, time.Millisecond)
+		assert.GreaterOrEqual(t, time.Since(start), time.Millisecond*50)
+		cancel()
+		assert.Nil(t, <-done)
+	})
+}
+
+func TestBootstrap_Uptime(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	r := NewMockRunner(ctrl)
+	r.EXPECT().Name().Return("testRunner").AnyTimes()
+	r.EXPECT().Run(gomock.Any()).DoAndReturn(func(ctx context.Context) error {
+		<-ctx.Done()
+		return nil
+	})
+	r.EXPECT().Stop(gomock.Any()).Return(nil)
+	b := New(WithRunners(r))
+	assert.True(t, b.StartedAt().IsZero())
+	assert.Zero(t, b.Uptime())
+	before := time.Now()
+	done := make(chan error)
+	go func() {
+		done <- b.Run(ctx)
+	}()
+	assert.Eventually(t, b.Ready, time.Second, time.Millisecond)
+	assert.False(t, b.StartedAt().Before(before))
+	uptime := b.Uptime()
+	time.Sleep(time.Millisecond * 10)
+	assert.GreaterOrEqual(t, b.Uptime(), uptime+time.Millisecond*10)
+	cancel()
+	assert.Nil(t, <-done)
+	// Stopped, it no longer grows.
+	uptime = b.Uptime()
+	assert.NotZero(t, uptime)
+	time.Sleep(time.Millisecond * 10)
+	assert.Equal(t, uptime, b.Uptime())
+}
+
+func TestBootstrap_Value(t *testing.T) {
+	type key struct{}
+	b1, b2 := New(), New()
+	assert.Nil(t, b1.Value(key{}))
+	b1.SetValue(key{}, "b1")
+	b2.SetValue(key{}, "b2")
+	assert.Equal(t, "b1", b1.Value(key{}))
+	assert.Equal(t, "b2", b2.Value(key{}))
+	b1.SetValue(key{}, 1)
+	assert.Equal(t, 1, b1.Value(key{}))
+
+	var nilState bootstrap
+	nilState.SetValue(key{}, "value")
+	assert.Nil(t, nilState.Value(key{}))
+}
+
+func TestBootstrap_Stop(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	r := NewMockRunner(ctrl)
+	r.EXPECT().Name().Return("testRunner").AnyTimes()
+	stop := make(chan struct{})
+	r.EXPECT().Run(gomock.Any()).DoAndReturn(func(ctx context.Context) error {
+		<-stop
+		return nil
+	})
+	r.EXPECT().Stop(gomock.Any()).DoAndReturn(func(ctx context.Context) error {
+		close(stop)
+		return nil
+	})
+	b := New(WithRunners(r))
+	assert.ErrorIs(t, b.Stop(context.Background()), ErrNotRunning)
+	done := make(chan error)
+	go func() {
+		done <- b.Run(context.Background())
+	}()
+	assert.Eventually(t, b.Ready, time.Second, time.Millisecond)
+	assert.Nil(t, b.Stop(context.Background()))
+	select {
+	case err := <-done:
+		assert.Nil(t, err)
+	case <-time.After(time.Second):
+		t.Error("Run not returned")
+	}
+	assert.ErrorIs(t, b.Stop(context.Background()), ErrNotRunning)
+}
+
+func TestBootstrap_Stop_beforeStart(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	r := NewMockRunner(ctrl)
+	r.EXPECT().Name().Return("testRunner").AnyTimes()
+	var b Bootstrap
+	b = New(WithRunners(r), WithBeforeRun(func(ctx context.Context) error {
+		return b.Stop(ctx)
+	}))
+	done := make(chan error)
+	go func() {
+		done <- b.Run(context.Background())
+	}()
+	select {
+	case err := <-done:
+		assert.ErrorIs(t, err, ErrStoppedBeforeStart)
+	case <-time.After(time.Second):
+		t.Fatal("Run not returned")
+	}
+	assert.ErrorIs(t, b.Stop(context.Background()), ErrNotRunning)
+}
+
+func TestBootstrap_Start(t *testing.T) {
+	t.Run("stop", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		r := readierRunner{MockRunner: NewMockRunner(ctrl), waitReady: func(ctx context.Context) error {
+			time.Sleep(time.Millisecond * 20)
+			return nil
+		}}
+		r.EXPECT().Name().Return("testRunner").AnyTimes()
+		stop := make(chan struct{})
+		r.EXPECT().Run(gomock.Any()).DoAndReturn(func(ctx context.Context) error {
+			<-stop
+			return nil
+		})
+		r.EXPECT().Stop(gomock.Any()).DoAndReturn(func(ctx context.Context) error {
+			close(stop)
+			return nil
+		})
+		b := New(WithRunners(r))
+		assert.Nil(t, b.Start(context.Background()))
+		assert.True(t, b.Ready())
+		assert.Nil(t, b.Stop(context.Background()))
+		assert.False(t, b.Ready())
+		assert.ErrorIs(t, b.Stop(context.Background()), ErrNotRunning)
+	})
+	t.Run("failed", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		readyErr := errors.New("test")
+		r := readierRunner{MockRunner: NewMockRunner(ctrl), waitReady: func(ctx context.Context) error {
+			return readyErr
+		}}
+		r.EXPECT().Name().Return("testRunner").AnyTimes()
+		r.EXPECT().Run(gomock.Any()).DoAndReturn(func(ctx context.Context) error {
+			<-ctx.Done()
+			return nil
+		})
+		r.EXPECT().Stop(gomock.Any()).Return(nil).AnyTimes()
+		b := New(WithRunners(r))
+		assert.ErrorIs(t, b.Start(context.Background()), readyErr)
+		assert.ErrorIs(t, b.Stop(context.Background()), ErrNotRunning)
+	})
+	t.Run("ended", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		runErr := errors.New("test")
+		r := NewMockRunner(ctrl)
+		r.EXPECT().Name().Return("testRunner").AnyTimes()
+		run := make(chan struct{})
+		r.EXPECT().Run(gomock.Any()).DoAndReturn(func(ctx context.Context) error {
+			<-run
+			return runErr
+		})
+		r.EXPECT().Stop(gomock.Any()).Return(nil).AnyTimes()
+		b := New(WithRunners(r))
+		assert.Nil(t, b.Start(context.Background()))
+		close(run)
+		assert.Eventually(t, func() bool { return !b.Ready() }, time.Second, time.Millisecond)
+		assert.ErrorIs(t, b.Stop(context.Background()), runErr)
+	})
+	newRunning := func(ctrl *gomock.Controller) *MockRunner {
+		r := NewMockRunner(ctrl)
+		r.EXPECT().Name().Return("testRunner").AnyTimes()
+		r.EXPECT().Run(gomock.Any()).DoAndReturn(func(ctx context.Context) error {
+			<-ctx.Done()
+			return nil
+		})
+		r.EXPECT().Stop(gomock.Any()).Return(nil)
+		return r
+	}
+	startWithin := func(t *testing.T, b Bootstrap) {
+		started := make(chan error)
+		go func() {
+			started <- b.Start(context.Background())
+		}()
+		select {
+		case err := <-started:
+			assert.Nil(t, err)
+		case <-time.After(time.Second):
+			t.Fatal("Start not returned")
+		}
+		assert.Nil(t, b.Stop(context.Background()))
+	}
+	t.Run("optional_not_ready", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		optional := readierRunner{MockRunner: NewMockRunner(ctrl), waitReady: func(ctx context.Context) error {
+			return errors.New("test")
+		}}
+		optional.EXPECT().Name().Return("optional").AnyTimes()
+		optional.EXPECT().Run(gomock.Any()).DoAndReturn(func(ctx context.Context) error {
+			<-ctx.Done()
+			return nil
+		}).AnyTimes()
+		optional.EXPECT().Stop(gomock.Any()).Return(nil).AnyTimes()
+		startWithin(t, New(WithRunners(newRunning(ctrl)), WithOptionalRunners(optional)))
+	})
+	t.Run("skipped", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		req := EnvRequirement("BOOTSTRAP_TEST_MISSING_ENV")
+		req.Skip = true
+		skipped := requirerRunner{MockRunner: NewMockRunner(ctrl), requirements: []Requirement{req}}
+		skipped.EXPECT().Name().Return("skipped").AnyTimes()
+		skipped.EXPECT().Stop(gomock.Any()).Return(nil).AnyTimes()
+		startWithin(t, New(WithRunners(newRunning(ctrl), skipped)))
+	})
+}
+
+func TestBootstrap_LastError(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	runErr := errors.New("test")
+	failing := NewMockRunner(ctrl)
+	failing.EXPECT().Name().Return("failing").AnyTimes()
+	failing.EXPECT().Run(gomock.Any()).Return(runErr)
+	failing.EXPECT().Stop(gomock.Any()).Return(nil).AnyTimes()
+	r := NewMockRunner(ctrl)
+	r.EXPECT().Name().Return("testRunner").AnyTimes()
+	r.EXPECT().Run(gomock.Any()).DoAndReturn(func(ctx context.Context) error {
+		<-ctx.Done()
+		return nil
+	})
+	r.EXPECT().Stop(gomock.Any()).Return(nil).AnyTimes()
+	b := New(WithRunners(failing, r))
+	assert.ErrorIs(t, b.Run(context.Background()), runErr)
+	assert.Same(t, runErr, b.LastError("failing"))
+	assert.Nil(t, b.LastError("testRunner"))
+
+	var nilState bootstrap
+	assert.Nil(t, nilState.LastError("failing"))
+}
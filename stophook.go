@@ -0,0 +1,41 @@
+package bootstrap
+
+import (
+	"context"
+	"sort"
+)
+
+// stopHook is a func run before the runners stop, see WithBeforeStopPriority.
+type stopHook struct {
+	priority int
+	fn       func(ctx context.Context) error
+}
+
+// WithBeforeStopPriority adds fn to the hooks run once the shutdown begins, before the runners stop,
+// e.g. to flush metrics before the exporter closes. Hooks run one at a time in ascending priority,
+// the ones of equal priority in the order they were added. An error of fn is logged.
+func WithBeforeStopPriority(priority int, fn func(ctx context.Context) error) Option {
+	return func(b *bootstrap) {
+		if fn == nil {
+			return
+		}
+		b.beforeStop = append(b.beforeStop, stopHook{priority: priority, fn: fn})
+	}
+}
+
+// runBeforeStop runs the before stop hooks with the shutdown context ctx.
+func (l *lifecycle) runBeforeStop(ctx context.Context) {
+	if len(l.b.beforeStop) == 0 {
+		return
+	}
+	hooks := append([]stopHook(nil), l.b.beforeStop...)
+	sort.SliceStable(hooks, func(i, j int) bool {
+		return hooks[i].priority < hooks[j].priority
+	})
+	for _, h := range hooks {
+		if err := h.fn(ctx); err != nil {
+			l.logger.Error("Before stop hook failed", err)
+			l.b.warn("", "before stop hook failed", err)
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package bootstrap
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithBeforeStopPriority(t *testing.T) {
+	b := bootstrap{}
+	WithBeforeStopPriority(1, nil)(&b)
+	assert.Empty(t, b.beforeStop)
+	WithBeforeStopPriority(1, func(ctx context.Context) error {
+		return nil
+	})(&b)
+	if assert.Len(t, b.beforeStop, 1) {
+		assert.Equal(t, 1, b.beforeStop[0].priority)
+	}
+}
+
+func TestBootstrap_Run_beforeStop(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var order []string
+	orderMux := &sync.Mutex{}
+	record := func(name string) {
+		orderMux.Lock()
+		defer orderMux.Unlock()
+		order = append(order, name)
+	}
+	r := NewMockRunner(ctrl)
+	r.EXPECT().Name().Return("testRunner").AnyTimes()
+	r.EXPECT().Run(gomock.Any()).DoAndReturn(func(ctx context.Context) error {
+		<-ctx.Done()
+		return nil
+	})
+	r.EXPECT().Stop(gomock.Any()).DoAndReturn(func(ctx context.Context) error {
+		record("runner")
+		return nil
+	})
+	hook := func(name string, err error) func(ctx context.Context) error {
+		return func(ctx context.Context) error {
+			record(name)
+			return err
+		}
+	}
+	hookErr := errors.New("test")
+	b := New(WithRunners(r),
+		WithBeforeStopPriority(10, hook("close exporter", nil)),
+		WithBeforeStopPriority(0, hook("flush metrics", hookErr)),
+		WithBeforeStopPriority(10, hook("close db", nil)),
+	)
+	done := make(chan error)
+	go func() {
+		done <- b.Run(ctx)
+	}()
+	assert.Eventually(t, b.Ready, time.Second, time.Millisecond)
+	cancel()
+	assert.Nil(t, <-done)
+	assert.Equal(t, []string{"flush metrics", "close exporter", "close db", "runner"}, order)
+	if assert.Len(t, b.Warnings(), 1) {
+		assert.Equal(t, "before stop hook failed", b.Warnings()[0].Message)
+		assert.Same(t, hookErr, b.Warnings()[0].Err)
+	}
+}
@@ -0,0 +1,91 @@
+package bootstrap
+
+import (
+	"context"
+	"sort"
+
+	"github.com/pkg/errors"
+	"github.com/yimi-go/runner"
+)
+
+// checkStopOrder returns an error wrapping ErrStopOrderConflict if the stop order stops a runner
+// before one depending on it.
+func (b bootstrap) checkStopOrder() error {
+	for i, name := range b.stopOrder {
+		for _, later := range b.stopOrder[i+1:] {
+			if b.dependsOn(later, name) {
+				return errors.WithMessagef(ErrStopOrderConflict, "%s stops before %s, which depends on it", name, later)
+			}
+		}
+	}
+	return nil
+}
+
+// hasStopOrder tells whether the runners stop one after the other, see WithStopOrder and
+// WithShutdownOrderFunc.
+func (b bootstrap) hasStopOrder() bool {
+	return len(b.stopOrder) > 0 || b.stopLess != nil
+}
+
+// stopSequence returns the names of the runners of rs in their stop order: the listed runners in order,
+// then the others in registration order, or all of them sorted by the shutdown order func if set,
+// moving runners before their dependencies if needed.
+func (b bootstrap) stopSequence(rs []runner.Runner) []string {
+	pending := append([]string(nil), b.stopOrder...)
+	rs = append([]runner.Runner(nil), rs...)
+	if b.stopLess != nil {
+		sort.SliceStable(rs, func(i, j int) bool {
+			return b.stopLess(rs[i], rs[j])
+		})
+	}
+	for _, r := range rs {
+		if name := r.Name(); !containsString(pending, name) {
+			pending = append(pending, name)
+		}
+	}
+	sequence := make([]string, 0, len(pending))
+	for len(pending) > 0 {
+		next := 0
+	pick:
+		for i, name := range pending {
+			for _, other := range pending {
+				if other != name && b.dependsOn(other, name) {
+					// other has to stop first.
+					continue pick
+				}
+			}
+			next = i
+			break
+		}
+		sequence = append(sequence, pending[next])
+		pending = append(pending[:next], pending[next+1:]...)
+	}
+	return sequence
+}
+
+// awaitStopOrder waits for the runners stopping before the one named name to be stopped, or ctx
+// to be done. See WithStopOrder.
+func (l *lifecycle) awaitStopOrder(ctx context.Context, name string) {
+	if !l.b.hasStopOrder() {
+		return
+	}
+	var waits []chan struct{}
+	sequence := l.b.stopSequence(l.runners())
+	l.stopMux.Lock()
+	for _, other := range sequence {
+		if other == name {
+			break
+		}
+		if stopped, ok := l.stopRegistered[other]; ok {
+			waits = append(waits, stopped)
+		}
+	}
+	l.stopMux.Unlock()
+	for _, stopped := range waits {
+		select {
+		case <-stopped:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
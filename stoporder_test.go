@@ -0,0 +1,127 @@
+package bootstrap
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/yimi-go/runner"
+)
+
+func Test_bootstrap_checkStopOrder(t *testing.T) {
+	b := bootstrap{}
+	WithDependency("c", "b")(&b)
+	WithDependency("b", "a")(&b)
+	WithStopOrder("c", "a")(&b)
+	assert.Nil(t, b.checkStopOrder())
+	WithStopOrder("b")(&b)
+	err := b.checkStopOrder()
+	assert.ErrorIs(t, err, ErrStopOrderConflict)
+	assert.Contains(t, err.Error(), "a stops before b, which depends on it")
+}
+
+func TestBootstrap_Run_stopOrder(t *testing.T) {
+	run := func(t *testing.T, names []string, opts ...Option) []string {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		var mux sync.Mutex
+		var stopped []string
+		var runners []runner.Runner
+		for _, name := range names {
+			name := name
+			r := NewMockRunner(ctrl)
+			r.EXPECT().Name().Return(name).AnyTimes()
+			r.EXPECT().Run(gomock.Any()).DoAndReturn(func(ctx context.Context) error {
+				<-ctx.Done()
+				return nil
+			})
+			r.EXPECT().Stop(gomock.Any()).DoAndReturn(func(ctx context.Context) error {
+				// Give the runners stopping out of order a chance to.
+				time.Sleep(time.Millisecond * 5)
+				mux.Lock()
+				stopped = append(stopped, name)
+				mux.Unlock()
+				return nil
+			})
+			runners = append(runners, r)
+		}
+		b := New(append([]Option{WithRunners(runners...)}, opts...)...)
+		done := make(chan error)
+		go func() {
+			done <- b.Run(ctx)
+		}()
+		assert.Eventually(t, b.Ready, time.Second, time.Millisecond)
+		cancel()
+		assert.Nil(t, <-done)
+		return stopped
+	}
+	t.Run("explicit", func(t *testing.T) {
+		stopped := run(t, []string{"a", "b", "c"}, WithStopOrder("b", "c", "a"))
+		assert.Equal(t, []string{"b", "c", "a"}, stopped)
+	})
+	t.Run("not_listed", func(t *testing.T) {
+		stopped := run(t, []string{"a", "b", "c", "d"}, WithStopOrder("c"))
+		assert.Equal(t, []string{"c", "a", "b", "d"}, stopped)
+	})
+	t.Run("dependencies", func(t *testing.T) {
+		// d depends on a, so it stops before a despite the registration order.
+		stopped := run(t, []string{"a", "b", "c", "d"}, WithStopOrder("c"), WithDependency("d", "a"))
+		assert.Equal(t, []string{"c", "b", "d", "a"}, stopped)
+	})
+	t.Run("conflict", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		a, b := NewMockRunner(ctrl), NewMockRunner(ctrl)
+		a.EXPECT().Name().Return("a").AnyTimes()
+		b.EXPECT().Name().Return("b").AnyTimes()
+		err := New(WithRunners(a, b), WithDependency("b", "a"), WithStopOrder("a", "b")).Run(context.Background())
+		assert.ErrorIs(t, err, ErrStopOrderConflict)
+	})
+	// bySuffix orders the runners by the number suffixing their names, e.g. db-1 before api-2.
+	bySuffix := WithShutdownOrderFunc(func(a, b runner.Runner) bool {
+		return a.Name()[strings.LastIndex(a.Name(), "-")+1:] < b.Name()[strings.LastIndex(b.Name(), "-")+1:]
+	})
+	t.Run("order_func", func(t *testing.T) {
+		stopped := run(t, []string{"api-2", "db-3", "worker-2", "cache-1"}, bySuffix)
+		assert.Equal(t, []string{"cache-1", "api-2", "worker-2", "db-3"}, stopped)
+	})
+	t.Run("order_func_dependencies", func(t *testing.T) {
+		// db-3 depends on cache-1, so it stops before it whatever the order func tells.
+		stopped := run(t, []string{"db-3", "api-2", "cache-1"}, bySuffix, WithDependency("db-3", "cache-1"))
+		assert.Equal(t, []string{"api-2", "db-3", "cache-1"}, stopped)
+	})
+	t.Run("order_func_overrides", func(t *testing.T) {
+		stopped := run(t, []string{"api-2", "cache-1"}, WithStopOrder("api-2"), bySuffix)
+		assert.Equal(t, []string{"cache-1", "api-2"}, stopped)
+	})
+	t.Run("overridden_by_stop_order", func(t *testing.T) {
+		stopped := run(t, []string{"api-2", "cache-1"}, bySuffix, WithStopOrder("api-2"))
+		assert.Equal(t, []string{"api-2", "cache-1"}, stopped)
+	})
+}
+
+func TestBootstrap_Run_stopOrderOverridden(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	r := NewMockRunner(ctrl)
+	r.EXPECT().Name().Return("a").AnyTimes()
+	r.EXPECT().Run(gomock.Any()).Return(nil).AnyTimes()
+	r.EXPECT().Stop(gomock.Any()).Return(nil).AnyTimes()
+	b := New(WithRunners(r), WithStopOrder("a"), WithShutdownOrderFunc(func(a, b runner.Runner) bool {
+		return false
+	}))
+	_ = b.Run(ctx)
+	warnings := b.Warnings()
+	if assert.NotEmpty(t, warnings) {
+		assert.Equal(t, "stop order overridden", warnings[0].Message)
+	}
+}
@@ -0,0 +1,71 @@
+package bootstrap
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/yimi-go/runner"
+	"golang.org/x/exp/slog"
+)
+
+// SupervisorConfig is the restart policy of a supervised runner, see WithSupervised.
+type SupervisorConfig struct {
+	// MaxRestarts is the number of restarts before the failure is escalated. Negative is unlimited.
+	MaxRestarts int
+	// Backoff is the wait before a restart.
+	Backoff time.Duration
+	// RecoverPanics makes a panic of the runner a failure, restarted as any other.
+	// Otherwise, it crashes the process.
+	RecoverPanics bool
+}
+
+// supervise runs r, restarting it on failure as cfg tells, until it returns nil, the bootstrap
+// stops, or the restarts are exhausted, in which case its last failure is returned.
+func (l *lifecycle) supervise(ctx context.Context, r runner.Runner, cfg SupervisorConfig) error {
+	for restarts := 0; ; restarts++ {
+		err := l.runSupervised(ctx, r, cfg)
+		if err == nil || l.stopped(ctx) {
+			return err
+		}
+		if cfg.MaxRestarts >= 0 && restarts >= cfg.MaxRestarts {
+			return errors.WithMessagef(err, "restarted %d times", restarts)
+		}
+		if l.logger.Enabled(slog.WarnLevel) {
+			l.logger.Warn(fmt.Sprintf("Runner %s failed, restarting in %s", r.Name(), cfg.Backoff),
+				slog.Int("restarts", restarts), slog.Any(slog.ErrorKey, err))
+		}
+		select {
+		case <-l.b.clk().After(cfg.Backoff):
+		case <-ctx.Done():
+			return nil
+		}
+		if l.stopped(ctx) {
+			return nil
+		}
+		l.b.warn(r.Name(), "restarted", err)
+	}
+}
+
+// runSupervised runs r once, recovering its panic if cfg tells.
+func (l *lifecycle) runSupervised(ctx context.Context, r runner.Runner, cfg SupervisorConfig) (err error) {
+	if cfg.RecoverPanics {
+		defer func() {
+			if p := recover(); p != nil {
+				err = errors.Errorf("%s panicked: %v", r.Name(), p)
+				if l.logger.Enabled(slog.ErrorLevel) {
+					l.logger.Error(fmt.Sprintf("Runner %s panicked", r.Name()), err,
+						slog.String("stack", string(debug.Stack())))
+				}
+			}
+		}()
+	}
+	return l.runWithRetry(ctx, r)
+}
+
+// stopped reports whether the runners are to stop: the shutdown began, or ctx is done.
+func (l *lifecycle) stopped(ctx context.Context) bool {
+	return ctx.Err() != nil || l.b.state.load() == phaseStopping
+}
@@ -0,0 +1,65 @@
+package bootstrap
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBootstrap_Run_supervised(t *testing.T) {
+	t.Run("restarted", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		var runs atomic.Int32
+		stop := make(chan struct{})
+		r := NewMockRunner(ctrl)
+		r.EXPECT().Name().Return("testRunner").AnyTimes()
+		r.EXPECT().Run(gomock.Any()).DoAndReturn(func(ctx context.Context) error {
+			if runs.Add(1) == 1 {
+				panic("test")
+			}
+			<-stop
+			return nil
+		}).Times(2)
+		r.EXPECT().Stop(gomock.Any()).DoAndReturn(func(ctx context.Context) error {
+			close(stop)
+			return nil
+		})
+		b := New(WithRunners(r), WithSupervised("testRunner", SupervisorConfig{
+			MaxRestarts:   1,
+			Backoff:       time.Millisecond,
+			RecoverPanics: true,
+		}))
+		done := make(chan error)
+		go func() {
+			done <- b.Run(context.Background())
+		}()
+		assert.Eventually(t, func() bool { return runs.Load() == 2 }, time.Second, time.Millisecond)
+		assert.Nil(t, b.Stop(context.Background()))
+		assert.Nil(t, <-done)
+		if warnings := b.Warnings(); assert.Len(t, warnings, 1) {
+			assert.Equal(t, "restarted", warnings[0].Message)
+			assert.ErrorContains(t, warnings[0].Err, "testRunner panicked: test")
+		}
+	})
+	t.Run("escalated", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		runErr := errors.New("test")
+		r := NewMockRunner(ctrl)
+		r.EXPECT().Name().Return("testRunner").AnyTimes()
+		r.EXPECT().Run(gomock.Any()).Return(runErr).Times(3)
+		r.EXPECT().Stop(gomock.Any()).Return(nil).AnyTimes()
+		err := New(WithRunners(r), WithSupervised("testRunner", SupervisorConfig{
+			MaxRestarts: 2,
+			Backoff:     time.Millisecond,
+		})).Run(context.Background())
+		assert.ErrorIs(t, err, runErr)
+		assert.ErrorContains(t, err, "restarted 2 times")
+	})
+}
@@ -0,0 +1,135 @@
+// Package testutil helps testing the integration of runners with a bootstrap.
+package testutil
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/yimi-go/bootstrap"
+)
+
+// RunAndStop runs b, waits for it to be ready, cancels its context and waits for it to stop,
+// all within the given duration. It reports whether b started and stopped cleanly, failing t
+// otherwise.
+func RunAndStop(t testing.TB, b bootstrap.Bootstrap, within time.Duration) bool {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	timeout := time.NewTimer(within)
+	defer timeout.Stop()
+	done := make(chan error, 1)
+	go func() {
+		done <- b.Run(ctx)
+	}()
+	ticker := time.NewTicker(time.Millisecond)
+	defer ticker.Stop()
+	for !b.Ready() {
+		select {
+		case err := <-done:
+			t.Errorf("bootstrap stopped before it was ready: %v", err)
+			return false
+		case <-timeout.C:
+			t.Errorf("bootstrap not ready within %s", within)
+			return false
+		case <-ticker.C:
+		}
+	}
+	cancel()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("bootstrap stopped with an error: %v", err)
+			return false
+		}
+		return true
+	case <-timeout.C:
+		t.Errorf("bootstrap not stopped within %s", within)
+		return false
+	}
+}
+
+// Runner is a fake runner, running until it is stopped or its context is done.
+// Build it with NewRunner, then the With methods.
+type Runner struct {
+	name    string
+	runErr  error
+	stopErr error
+	runs    atomic.Int32
+	stops   atomic.Int32
+
+	mux     sync.Mutex
+	stopped chan struct{}
+}
+
+// NewRunner returns a fake runner named name.
+func NewRunner(name string) *Runner {
+	return &Runner{name: name}
+}
+
+// WithRunError makes the runs of r fail with err right away.
+func (r *Runner) WithRunError(err error) *Runner {
+	r.runErr = err
+	return r
+}
+
+// WithStopError makes the stops of r fail with err.
+func (r *Runner) WithStopError(err error) *Runner {
+	r.stopErr = err
+	return r
+}
+
+// Name returns the name of r.
+func (r *Runner) Name() string {
+	return r.name
+}
+
+// Run runs r until it is stopped or ctx is done, or fails with its run error.
+func (r *Runner) Run(ctx context.Context) error {
+	r.runs.Add(1)
+	if r.runErr != nil {
+		return r.runErr
+	}
+	select {
+	case <-r.stoppedChan():
+	case <-ctx.Done():
+	}
+	return nil
+}
+
+// Stop stops r, returning its stop error.
+func (r *Runner) Stop(ctx context.Context) error {
+	r.stops.Add(1)
+	r.mux.Lock()
+	defer r.mux.Unlock()
+	if r.stopped == nil {
+		r.stopped = make(chan struct{})
+	}
+	select {
+	case <-r.stopped:
+	default:
+		close(r.stopped)
+	}
+	return r.stopErr
+}
+
+// Runs returns the number of runs of r.
+func (r *Runner) Runs() int {
+	return int(r.runs.Load())
+}
+
+// Stops returns the number of stops of r.
+func (r *Runner) Stops() int {
+	return int(r.stops.Load())
+}
+
+func (r *Runner) stoppedChan() <-chan struct{} {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+	if r.stopped == nil {
+		r.stopped = make(chan struct{})
+	}
+	return r.stopped
+}
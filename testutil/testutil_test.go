@@ -0,0 +1,89 @@
+package testutil
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/yimi-go/bootstrap"
+)
+
+// recordingTB records the errors reported to it.
+type recordingTB struct {
+	testing.TB
+	errs []string
+}
+
+func (tb *recordingTB) Helper() {}
+
+func (tb *recordingTB) Errorf(format string, args ...any) {
+	tb.errs = append(tb.errs, fmt.Sprintf(format, args...))
+}
+
+// hungRunner is a runner ignoring its stop and its context until released.
+type hungRunner struct {
+	released chan struct{}
+}
+
+func (r hungRunner) Name() string {
+	return "hung"
+}
+
+func (r hungRunner) Run(ctx context.Context) error {
+	<-r.released
+	return nil
+}
+
+func (r hungRunner) Stop(ctx context.Context) error {
+	return nil
+}
+
+func TestRunAndStop(t *testing.T) {
+	t.Run("clean", func(t *testing.T) {
+		r := NewRunner("a")
+		assert.True(t, RunAndStop(t, bootstrap.New(bootstrap.WithRunners(r)), time.Second))
+		assert.Equal(t, 1, r.Runs())
+		assert.Equal(t, 1, r.Stops())
+	})
+	t.Run("run_error", func(t *testing.T) {
+		tb := &recordingTB{TB: t}
+		r := NewRunner("a").WithRunError(errors.New("test"))
+		assert.False(t, RunAndStop(tb, bootstrap.New(bootstrap.WithRunners(r)), time.Second))
+		if assert.Len(t, tb.errs, 1) {
+			assert.Contains(t, tb.errs[0], "test")
+		}
+	})
+	t.Run("stop_error", func(t *testing.T) {
+		tb := &recordingTB{TB: t}
+		r := NewRunner("a").WithStopError(errors.New("test"))
+		b := bootstrap.New(bootstrap.WithRunners(r), bootstrap.WithReturnStopErrors(true))
+		assert.False(t, RunAndStop(tb, b, time.Second))
+		if assert.Len(t, tb.errs, 1) {
+			assert.Contains(t, tb.errs[0], "bootstrap stopped with an error")
+		}
+	})
+	t.Run("not_stopped", func(t *testing.T) {
+		tb := &recordingTB{TB: t}
+		r := hungRunner{released: make(chan struct{})}
+		defer close(r.released)
+		assert.False(t, RunAndStop(tb, bootstrap.New(bootstrap.WithRunners(r)), time.Millisecond*100))
+		assert.Equal(t, []string{"bootstrap not stopped within 100ms"}, tb.errs)
+	})
+}
+
+func TestRunner(t *testing.T) {
+	r := NewRunner("a")
+	assert.Equal(t, "a", r.Name())
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.Nil(t, r.Run(ctx))
+	assert.Nil(t, r.Stop(context.Background()))
+	assert.Nil(t, r.Stop(context.Background()))
+	assert.Nil(t, r.Run(context.Background()))
+	assert.Equal(t, 2, r.Runs())
+	assert.Equal(t, 2, r.Stops())
+}
@@ -0,0 +1,40 @@
+package bootstrap
+
+import (
+	"encoding/json"
+)
+
+// topologyRunner describes a runner in the topology of a bootstrap.
+type topologyRunner struct {
+	Name string `json:"name"`
+	// Group is the shutdown phase of the runner, see WithRunnerGroup.
+	Group     int      `json:"group"`
+	Priority  int      `json:"priority"`
+	DependsOn []string `json:"depends_on,omitempty"`
+	Optional  bool     `json:"optional,omitempty"`
+}
+
+// Topology returns the JSON description of the runners the bootstrap orchestrates, in start order:
+// their names, groups, priorities and dependencies. Runners produced by factories are not known
+// before Run, and not described.
+func (b bootstrap) Topology() ([]byte, error) {
+	rs := b.runnerSet()
+	entries := make([]*runnerEntry, 0, len(rs))
+	for _, r := range rs {
+		entries = append(entries, &runnerEntry{r: r})
+	}
+	runners := make([]topologyRunner, 0, len(entries))
+	for _, e := range b.sortEntries(entries) {
+		name := e.r.Name()
+		runners = append(runners, topologyRunner{
+			Name:      name,
+			Group:     b.phases[name],
+			Priority:  priorityOf(e.r),
+			DependsOn: b.dependencies[name],
+			Optional:  b.isOptional(e.r),
+		})
+	}
+	return json.Marshal(struct {
+		Runners []topologyRunner `json:"runners"`
+	}{Runners: runners})
+}
@@ -0,0 +1,35 @@
+package bootstrap
+
+import (
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBootstrap_Topology(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	newRunner := func(name string) *MockRunner {
+		r := NewMockRunner(ctrl)
+		r.EXPECT().Name().Return(name).AnyTimes()
+		return r
+	}
+	db, cache, api := newRunner("db"), newRunner("cache"), newRunner("api")
+	b := New(
+		WithRunners(api),
+		WithOptionalRunners(cache),
+		WithRunnerGroup(1, db),
+		WithDependency("api", "db"),
+		WithDependency("api", "cache"),
+	)
+	assert.Nil(t, b.AddRunner(priorityRunner{MockRunner: newRunner("metrics"), priority: 10}))
+	topology, err := b.Topology()
+	assert.Nil(t, err)
+	assert.JSONEq(t, `{"runners": [
+		{"name": "cache", "group": 0, "priority": 0, "optional": true},
+		{"name": "db", "group": 1, "priority": 0},
+		{"name": "metrics", "group": 0, "priority": 10},
+		{"name": "api", "group": 0, "priority": 0, "depends_on": ["db", "cache"]}
+	]}`, string(topology))
+}
@@ -0,0 +1,38 @@
+package bootstrap
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const tracerName = "github.com/yimi-go/bootstrap"
+
+// startSpan starts a span if tracing is configured. Otherwise, ctx and a nil span are returned.
+func (b bootstrap) startSpan(ctx context.Context, name string) (context.Context, trace.Span) {
+	if b.tracer == nil {
+		return ctx, nil
+	}
+	return b.tracer.Start(ctx, name)
+}
+
+// startChildSpan starts a span in ctx as a child of the span carried by parent.
+func (b bootstrap) startChildSpan(ctx, parent context.Context, name string) (context.Context, trace.Span) {
+	if b.tracer == nil {
+		return ctx, nil
+	}
+	return b.tracer.Start(trace.ContextWithSpan(ctx, trace.SpanFromContext(parent)), name)
+}
+
+// endSpan records err on span and ends it. A nil span is ignored.
+func endSpan(span trace.Span, err error) {
+	if span == nil {
+		return
+	}
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
@@ -0,0 +1,62 @@
+package bootstrap
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func Test_endSpan(t *testing.T) {
+	endSpan(nil, errors.New("test"))
+}
+
+func TestBootstrap_Run_tracing(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	recorder := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	r := NewMockRunner(ctrl)
+	r.EXPECT().Name().Return("testRunner").AnyTimes()
+	r.EXPECT().Run(gomock.Any()).DoAndReturn(func(ctx context.Context) error {
+		<-ctx.Done()
+		return nil
+	})
+	r.EXPECT().Stop(gomock.Any()).Return(errors.New("test"))
+	b := New(WithRunners(r), WithTracerProvider(tp))
+	go func() {
+		<-time.After(time.Millisecond * 10)
+		cancel()
+	}()
+	assert.Nil(t, b.Run(ctx))
+
+	spans := map[string]sdktrace.ReadOnlySpan{}
+	for _, span := range recorder.Ended() {
+		spans[span.Name()] = span
+	}
+	assert.Len(t, spans, 4)
+	runSpan, ok := spans["bootstrap.run"]
+	if !assert.True(t, ok) {
+		return
+	}
+	assert.False(t, runSpan.Parent().IsValid())
+	for _, name := range []string{"runner.start/testRunner", "runner.run/testRunner", "runner.stop/testRunner"} {
+		span, ok := spans[name]
+		if assert.True(t, ok, name) {
+			assert.Equal(t, runSpan.SpanContext().SpanID(), span.Parent().SpanID(), name)
+			assert.Equal(t, runSpan.SpanContext().TraceID(), span.SpanContext().TraceID(), name)
+		}
+	}
+	assert.Equal(t, codes.Error, spans["runner.stop/testRunner"].Status().Code)
+	// The start span ends once the runner started, before it stops and returns.
+	assert.True(t, spans["runner.start/testRunner"].EndTime().Before(spans["runner.stop/testRunner"].StartTime()))
+	assert.True(t, spans["runner.start/testRunner"].EndTime().Before(spans["runner.run/testRunner"].EndTime()))
+}
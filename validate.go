@@ -0,0 +1,68 @@
+package bootstrap
+
+import (
+	"sort"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Validate checks the configuration of the bootstrap without starting anything, e.g. in CI.
+// It returns all the problems found, joined: no runners, duplicate runner names, dependency cycles,
+// ordering conflicts and negative timeouts. Runners produced by factories or start hooks are not checked.
+func (b bootstrap) Validate() error {
+	var errs []error
+	rs := b.runnerSet()
+	if !b.hasWork() {
+		errs = append(errs, ErrNoRunners)
+	}
+	seen := map[string]int{}
+	for _, r := range rs {
+		seen[r.Name()]++
+		if seen[r.Name()] == 2 {
+			errs = append(errs, errors.WithMessage(ErrDuplicateRunner, r.Name()))
+		}
+	}
+	depErr := b.checkDependencies()
+	errs = append(errs, depErr, b.checkStopOrder())
+	if depErr == nil {
+		errs = append(errs, b.checkOrdering(rs))
+	}
+	durations := map[string]time.Duration{
+		"stop timeout":       b.stopTimeout,
+		"ready delay":        b.readyDelay,
+		"min uptime":         b.minUptime,
+		"drain delay":        b.drainDelay,
+		"before run timeout": b.beforeRunTimeout,
+		"startup deadline":   b.startupDeadline,
+		"wait for timeout":   b.waitForTimeout,
+		"in-flight grace":    b.inFlightGrace,
+		"slow startup":       b.slowStartup,
+		"shutdown timeout":   b.shutdownTimeout,
+		"run timeout":        b.runTimeout,
+		"before run backoff": b.beforeRunBackoff,
+	}
+	for name, d := range b.runnerTimeouts {
+		durations["timeout of runner "+name] = d
+	}
+	for name, cfg := range b.supervised {
+		durations["restart backoff of runner "+name] = cfg.Backoff
+	}
+	for name, retry := range b.startRetries {
+		durations["retry backoff of runner "+name] = retry.backoff
+	}
+	for cause, d := range b.causeTimeouts {
+		durations["timeout of cause "+string(cause)] = d
+	}
+	names := make([]string, 0, len(durations))
+	for name, d := range durations {
+		if d < 0 {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		errs = append(errs, errors.Errorf("bootstrap: negative %s: %s", name, durations[name]))
+	}
+	return joinErrors(errs...)
+}
@@ -0,0 +1,67 @@
+package bootstrap
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/yimi-go/runner"
+)
+
+func TestBootstrap_Validate(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	newRunner := func(name string) *MockRunner {
+		r := NewMockRunner(ctrl)
+		r.EXPECT().Name().Return(name).AnyTimes()
+		return r
+	}
+	t.Run("valid", func(t *testing.T) {
+		b := New(WithRunners(newRunner("a"), newRunner("b")), WithDependency("b", "a"))
+		assert.Nil(t, b.Validate())
+	})
+	t.Run("no_runners", func(t *testing.T) {
+		assert.ErrorIs(t, New().Validate(), ErrNoRunners)
+		assert.Nil(t, New(WithRunnerFactory(func(ctx context.Context) ([]runner.Runner, error) {
+			return nil, nil
+		})).Validate())
+		// Run runs onRun or beforeRun as a job without runners.
+		assert.Nil(t, New(WithOnRun(func(ctx context.Context) error {
+			return nil
+		})).Validate())
+		assert.Nil(t, New(WithBeforeRun(func(ctx context.Context) error {
+			return nil
+		})).Validate())
+		assert.Nil(t, New(WithStartHook(func(ctx context.Context, current []runner.Runner) ([]runner.Runner, error) {
+			return current, nil
+		})).Validate())
+	})
+	t.Run("misconfigured", func(t *testing.T) {
+		b := New(
+			WithRunners(newRunner("a"), newRunner("b"), newRunner("a")),
+			WithDependency("a", "b"),
+			WithDependency("b", "a"),
+			WithStopTimeout(-time.Second),
+		)
+		err := b.Validate()
+		assert.ErrorIs(t, err, ErrDuplicateRunner)
+		assert.ErrorIs(t, err, ErrDependencyCycle)
+		assert.Contains(t, err.Error(), "a: bootstrap: duplicate runner name")
+		assert.Contains(t, err.Error(), "a -> b -> a")
+		assert.Contains(t, err.Error(), "bootstrap: negative stop timeout: -1s")
+	})
+	t.Run("negative_timeouts", func(t *testing.T) {
+		err := New(
+			WithRunners(newRunner("a")),
+			WithTimeouts(Timeouts{Shutdown: -time.Second}),
+			WithRunTimeout(-time.Second),
+			WithBeforeRunRetry(2, -time.Second),
+		).Validate()
+		assert.Contains(t, err.Error(), "bootstrap: negative shutdown timeout: -1s")
+		assert.Contains(t, err.Error(), "bootstrap: negative run timeout: -1s")
+		assert.Contains(t, err.Error(), "bootstrap: negative before run backoff: -1s")
+	})
+}
@@ -0,0 +1,58 @@
+package bootstrap
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+	"golang.org/x/exp/slog"
+	"golang.org/x/sync/errgroup"
+)
+
+// defaultWaitForTimeout bounds the wait for the checks, if no timeout is configured.
+const defaultWaitForTimeout = 30 * time.Second
+
+var (
+	// waitForBackoff is the initial wait between the attempts of a check, doubled after each one.
+	waitForBackoff = 100 * time.Millisecond
+	// waitForMaxBackoff is the maximum wait between the attempts of a check.
+	waitForMaxBackoff = 2 * time.Second
+)
+
+// waitFor runs the wait for checks concurrently, retrying each until it succeeds, or the wait for
+// timeout is exceeded. See WithWaitFor.
+func (b bootstrap) waitFor(ctx context.Context, logger *slog.Logger) error {
+	timeout := b.waitForTimeout
+	if timeout <= 0 {
+		timeout = defaultWaitForTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	eg, egCtx := errgroup.WithContext(ctx)
+	for i, check := range b.waitForChecks {
+		i, check := i, check
+		eg.Go(func() error {
+			backoff := waitForBackoff
+			for attempt := 1; ; attempt++ {
+				err := check(egCtx)
+				if err == nil {
+					return nil
+				}
+				if logger.Enabled(slog.WarnLevel) {
+					logger.Warn(fmt.Sprintf("Wait for check #%d failed, retrying in %s", i, backoff),
+						slog.Int("attempt", attempt), slog.Any(slog.ErrorKey, err))
+				}
+				select {
+				case <-b.clk().After(backoff):
+				case <-egCtx.Done():
+					return errors.WithMessagef(err, "wait for check #%d failed after %d attempts", i, attempt)
+				}
+				if backoff *= 2; backoff > waitForMaxBackoff {
+					backoff = waitForMaxBackoff
+				}
+			}
+		})
+	}
+	return eg.Wait()
+}
@@ -0,0 +1,64 @@
+package bootstrap
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBootstrap_Run_waitFor(t *testing.T) {
+	backoff := waitForBackoff
+	waitForBackoff = time.Millisecond
+	defer func() {
+		waitForBackoff = backoff
+	}()
+	t.Run("ready", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		var calls atomic.Int32
+		r := NewMockRunner(ctrl)
+		r.EXPECT().Name().Return("testRunner").AnyTimes()
+		r.EXPECT().Run(gomock.Any()).DoAndReturn(func(ctx context.Context) error {
+			// Started once the check succeeded.
+			assert.Equal(t, int32(3), calls.Load())
+			<-ctx.Done()
+			return nil
+		})
+		r.EXPECT().Stop(gomock.Any()).Return(nil)
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		b := New(WithRunners(r), WithWaitFor(func(ctx context.Context) error {
+			if calls.Add(1) < 3 {
+				return errors.New("unreachable")
+			}
+			return nil
+		}), WithOnRun(func(ctx context.Context) error {
+			cancel()
+			return nil
+		}))
+		assert.Nil(t, b.Run(ctx))
+		assert.Equal(t, int32(3), calls.Load())
+	})
+	t.Run("timeout", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+		r := NewMockRunner(ctrl)
+		r.EXPECT().Name().Return("testRunner").AnyTimes()
+		checkErr := errors.New("unreachable")
+		err := New(WithRunners(r), WithWaitForTimeout(time.Millisecond*20), WithWaitFor(
+			func(ctx context.Context) error {
+				return nil
+			},
+			func(ctx context.Context) error {
+				return checkErr
+			},
+		)).Run(context.Background())
+		assert.ErrorIs(t, err, checkErr)
+		assert.Contains(t, err.Error(), "wait for check #1 failed after")
+	})
+}
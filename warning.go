@@ -0,0 +1,52 @@
+package bootstrap
+
+// Warning is a non-fatal issue met during a Run, e.g. a runner failing to stop.
+type Warning struct {
+	// Runner is the name of the runner concerned, empty if none.
+	Runner  string
+	Message string
+	Err     error
+}
+
+func (w Warning) String() string {
+	s := w.Message
+	if w.Runner != "" {
+		s = w.Runner + ": " + s
+	}
+	if w.Err != nil {
+		s += ": " + w.Err.Error()
+	}
+	return s
+}
+
+// Warnings returns the warnings recorded during the last Run.
+func (b bootstrap) Warnings() []Warning {
+	if b.state == nil {
+		return nil
+	}
+	b.state.mux.Lock()
+	defer b.state.mux.Unlock()
+	return append([]Warning(nil), b.state.warnings...)
+}
+
+// warn records a warning about the runner named runner.
+func (b bootstrap) warn(runner, msg string, err error) {
+	if b.state == nil {
+		return
+	}
+	b.state.mux.Lock()
+	defer b.state.mux.Unlock()
+	b.state.warnings = append(b.state.warnings, Warning{Runner: runner, Message: msg, Err: err})
+}
+
+// reset clears the warnings and errors of a previous Run.
+func (s *runState) reset() {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	s.warnings = nil
+	s.lastErrors = nil
+	s.stopCause = nil
+	s.event = nil
+	s.reason.Store(int32(ReasonNone))
+	s.startedAt.Store(nil)
+}
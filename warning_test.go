@@ -0,0 +1,49 @@
+package bootstrap
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWarning_String(t *testing.T) {
+	assert.Equal(t, "msg", Warning{Message: "msg"}.String())
+	assert.Equal(t, "r: msg: err", Warning{Runner: "r", Message: "msg", Err: errors.New("err")}.String())
+}
+
+func TestBootstrap_Warnings(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	stopErr := errors.New("test")
+	r := NewMockRunner(ctrl)
+	r.EXPECT().Name().Return("testRunner").AnyTimes()
+	r.EXPECT().Run(gomock.Any()).DoAndReturn(func(ctx context.Context) error {
+		<-ctx.Done()
+		return nil
+	})
+	r.EXPECT().Stop(gomock.Any()).Return(stopErr)
+	b := New(WithRunners(r))
+	assert.Empty(t, b.Warnings())
+	done := make(chan error)
+	go func() {
+		done <- b.Run(ctx)
+	}()
+	assert.Eventually(t, b.Ready, time.Second, time.Millisecond)
+	cancel()
+	assert.Nil(t, <-done)
+	warnings := b.Warnings()
+	if assert.Len(t, warnings, 1) {
+		assert.Equal(t, "testRunner", warnings[0].Runner)
+		assert.ErrorIs(t, warnings[0].Err, stopErr)
+	}
+
+	var nilState bootstrap
+	nilState.warn("r", "msg", nil)
+	assert.Nil(t, nilState.Warnings())
+}